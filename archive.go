@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// magic bytes for the formats we dork for
+var (
+	pdfMagic = []byte("%PDF-")
+	zipMagic = []byte("PK\x03\x04")                       // Office Open XML
+	oleMagic = []byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1") // legacy Office
+)
+
+// anything that shouldn't go in a file name
+var unsafeFileNameRegex = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// checkDocument makes sure we got the document we asked for, and not
+// a login page or a soft 404 served under the same URL
+func checkDocument(buf []byte, fileType, contentType string) error {
+	var magic []byte
+	switch fileType {
+	case ".pdf":
+		// some PDFs have junk before the header, which readers allow
+		head := buf[:min(len(buf), 1024)]
+		if !bytes.Contains(head, pdfMagic) {
+			return fmt.Errorf("not a PDF")
+		}
+		return nil
+	case ".docx", ".docm", ".dotx", ".dotm", ".xlsx", ".xlsm", ".xltx", ".xltm", ".pptx", ".pptm", ".potx", ".potm":
+		magic = zipMagic
+	case ".doc", ".xls", ".ppt":
+		magic = oleMagic
+	default:
+		// no idea what it should be, but it shouldn't be a web page
+		if strings.HasPrefix(strings.ToLower(contentType), "text/html") {
+			return fmt.Errorf("got a web page (%s)", contentType)
+		}
+		return nil
+	}
+
+	if !bytes.HasPrefix(buf, magic) {
+		return fmt.Errorf("not a %s file", strings.TrimPrefix(fileType, "."))
+	}
+	return nil
+}
+
+// documentArchive saves fetched documents, and a manifest of where they
+// came from, as evidence
+type documentArchive struct {
+	mu   sync.Mutex
+	dir  string
+	seen map[string]bool // URL and SHA-256 pairs already in the manifest
+}
+
+// archiveManifestHeader is the first row of manifest.csv
+var archiveManifestHeader = []string{"file", "url", "fetched", "http_status", "size", "sha256", "content_type"}
+
+// newDocumentArchive opens the archive in dir, picking up the manifest
+// from earlier runs so the same document isn't listed twice
+func newDocumentArchive(dir string) (*documentArchive, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("unable to create archive directory: %w", err)
+	}
+	a := &documentArchive{dir: dir, seen: make(map[string]bool)}
+
+	f, err := os.Open(filepath.Join(dir, "manifest.csv"))
+	if os.IsNotExist(err) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to open archive manifest: %w", err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read archive manifest: %w", err)
+	}
+	for _, row := range rows {
+		if len(row) == len(archiveManifestHeader) {
+			a.seen[row[1]+" "+row[5]] = true
+		}
+	}
+	return a, nil
+}
+
+// archiveFileName makes a safe file name for a document. The hash
+// prefix keeps different documents with the same name apart.
+func archiveFileName(result analysisResult) string {
+	name := unsafeFileNameRegex.ReplaceAllString(docFileName(result.url), "_")
+	name = strings.Trim(name, "._")
+	if name == "" {
+		name = "document" + result.fileType
+	}
+	// leave plenty of room under the usual 255 limit
+	if len(name) > 100 {
+		name = name[len(name)-100:]
+	}
+	return result.sha256[:12] + "_" + name
+}
+
+// save writes a document to the archive and adds it to the manifest.
+// Identical documents with the same name are only written once, and
+// each URL only gets a new row when it serves something new. Anything
+// that isn't the type it claims is left out, but still gets analysed.
+func (a *documentArchive) save(result analysisResult, buf []byte) error {
+	if a == nil {
+		return nil
+	}
+	if err := checkDocument(buf, result.fileType, result.contentType); err != nil {
+		return fmt.Errorf("not archiving %s: %w", result.url, err)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	fileName := archiveFileName(result)
+	filePath := filepath.Join(a.dir, fileName)
+	written := false
+	if _, err := os.Stat(filePath); err != nil {
+		if err := os.WriteFile(filePath, buf, 0644); err != nil {
+			return fmt.Errorf("unable to archive %s: %w", result.url, err)
+		}
+		written = true
+	}
+	key := result.url + " " + result.sha256
+	if !written && a.seen[key] {
+		return nil
+	}
+
+	manifest := filepath.Join(a.dir, "manifest.csv")
+	_, err := os.Stat(manifest)
+	newManifest := os.IsNotExist(err)
+	f, err := os.OpenFile(manifest, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open archive manifest: %w", err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if newManifest {
+		w.Write(archiveManifestHeader)
+	}
+	w.Write([]string{
+		fileName,
+		result.url,
+		result.fetched.Format(time.RFC3339),
+		strconv.Itoa(result.httpStatus),
+		strconv.FormatInt(result.size, 10),
+		result.sha256,
+		result.contentType,
+	})
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("unable to write archive manifest: %w", err)
+	}
+	a.seen[key] = true
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Finding is a single value found in a document, flattened out of
+// whichever category it came from.
+type Finding struct {
+	Type     string `json:"type"`
+	Subtype  string `json:"subtype,omitempty"`
+	Value    string `json:"value,omitempty"`
+	FileName string `json:"file_name,omitempty"`
+	FileUrl  string `json:"file_url,omitempty"`
+}
+
+// findingCategories are the per-document categories, in the order
+// they're printed. The type doubles as the CSV file name.
+var findingCategories = []string{
+	"documents",
+	"external_links",
+	"image_links",
+	"file_paths",
+	"printers",
+	"hostnames",
+	"emails",
+	"names",
+	"usernames",
+	"grepped_values",
+	"hidden_sheets",
+	"last_saved_paths",
+	"software",
+	"fonts",
+	"secrets",
+	"network_intel",
+	"embedded_docs",
+	"embedded_media",
+}
+
+// flattenFindings turns the per-category results into one list of
+// findings, each with its type.
+func flattenFindings(results FinalResult) []Finding {
+	var findings []Finding
+	add := func(findingType, subtype, value, fileName, fileUrl string) {
+		findings = append(findings, Finding{
+			Type:     findingType,
+			Subtype:  subtype,
+			Value:    value,
+			FileName: fileName,
+			FileUrl:  fileUrl,
+		})
+	}
+
+	for _, d := range results.Documents {
+		add("documents", d.FileType, d.SearchEngine, d.FileName, d.FileUrl)
+	}
+	for _, l := range results.ExternalLinks {
+		add("external_links", "", l.ExternalLink, l.FileName, l.FileUrl)
+	}
+	for _, l := range results.ImageLinks {
+		add("image_links", "", l.ImageLink, l.FileName, l.FileUrl)
+	}
+	for _, p := range results.FilePaths {
+		add("file_paths", "", p.FilePath, p.FileName, p.FileUrl)
+	}
+	for _, p := range results.Printers {
+		add("printers", "", p.Printer, p.FileName, p.FileUrl)
+	}
+	for _, h := range results.Hostnames {
+		add("hostnames", "", h.Hostname, h.FileName, h.FileUrl)
+	}
+	for _, e := range results.Emails {
+		add("emails", "", e.EmailAddr, e.FileName, e.FileUrl)
+	}
+	for _, n := range results.Names {
+		add("names", "", n.Name, n.FileName, n.FileUrl)
+	}
+	for _, u := range results.Usernames {
+		add("usernames", "", u.UserName, u.FileName, u.FileUrl)
+	}
+	for _, g := range results.GreppedValues {
+		add("grepped_values", "", g.Value, g.FileName, g.FileUrl)
+	}
+	for _, s := range results.HiddenSheets {
+		add("hidden_sheets", "", s.SheetName, s.FileName, s.FileUrl)
+	}
+	for _, p := range results.LastSavedPaths {
+		add("last_saved_paths", "", p.Path, p.FileName, p.FileUrl)
+	}
+	for _, s := range results.Softwares {
+		add("software", "", s.Value, s.FileName, s.FileUrl)
+	}
+	for _, f := range results.Fonts {
+		add("fonts", "", f.Font, f.FileName, f.FileUrl)
+	}
+	for _, s := range results.Secrets {
+		add("secrets", s.Type, s.Value, s.FileName, s.FileUrl)
+	}
+	for _, n := range results.NetworkIntel {
+		add("network_intel", n.Type, n.Value, n.FileName, n.FileUrl)
+	}
+	for _, e := range results.EmbeddedDocs {
+		add("embedded_docs", "", "", e.FileName, e.FileUrl)
+	}
+	for _, e := range results.EmbeddedMedias {
+		add("embedded_media", "", "", e.FileName, e.FileUrl)
+	}
+
+	return findings
+}
+
+// csvValueColumn names the value column in each category's CSV
+var csvValueColumn = map[string]string{
+	"documents":        "search_engine",
+	"external_links":   "external_link",
+	"image_links":      "image_link",
+	"file_paths":       "file_path",
+	"printers":         "printer",
+	"hostnames":        "hostname",
+	"emails":           "email_address",
+	"names":            "name",
+	"usernames":        "username",
+	"grepped_values":   "value",
+	"hidden_sheets":    "sheet_name",
+	"last_saved_paths": "path",
+	"software":         "software",
+	"fonts":            "font",
+	"secrets":          "value",
+	"network_intel":    "value",
+}
+
+// csvSubtypeColumn names the extra column for categories that have one
+var csvSubtypeColumn = map[string]string{
+	"documents":     "file_type",
+	"secrets":       "type",
+	"network_intel": "type",
+}
+
+// writeCsvExport writes one CSV per category into dir, plus a flat
+// findings.csv with everything in it. Empty categories are skipped.
+func writeCsvExport(results FinalResult, dir string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create CSV output directory: %w", err)
+	}
+
+	findings := flattenFindings(results)
+
+	for _, category := range findingCategories {
+		var header []string
+		if col, ok := csvSubtypeColumn[category]; ok {
+			header = append(header, col)
+		}
+		if col, ok := csvValueColumn[category]; ok {
+			header = append(header, col)
+		}
+		header = append(header, "file_name", "file_url")
+
+		rows := [][]string{header}
+		for _, f := range findings {
+			if f.Type != category {
+				continue
+			}
+			var row []string
+			if _, ok := csvSubtypeColumn[category]; ok {
+				row = append(row, csvSafe(f.Subtype))
+			}
+			if _, ok := csvValueColumn[category]; ok {
+				row = append(row, csvSafe(f.Value))
+			}
+			rows = append(rows, append(row, csvSafe(f.FileName), csvSafe(f.FileUrl)))
+		}
+		if len(rows) == 1 {
+			continue
+		}
+
+		if err := writeCsvFile(filepath.Join(dir, category+".csv"), rows); err != nil {
+			return err
+		}
+	}
+
+	rows := [][]string{{"type", "subtype", "value", "file_name", "file_url"}}
+	for _, f := range findings {
+		rows = append(rows, []string{f.Type, csvSafe(f.Subtype), csvSafe(f.Value), csvSafe(f.FileName), csvSafe(f.FileUrl)})
+	}
+	return writeCsvFile(filepath.Join(dir, "findings.csv"), rows)
+}
+
+// csvSafe stops a value the target controls being run as a formula
+// when the CSV is opened in a spreadsheet
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
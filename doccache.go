@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// docCache keeps fetched documents on disk, named by their SHA-256, with
+// an index of which URL gave us which document. It's what lets us
+// re-analyse everything offline.
+type docCache struct {
+	mu    sync.Mutex
+	dir   string
+	index map[string]docIndexEntry
+}
+
+// docIndexEntry is one line of the index
+type docIndexEntry struct {
+	Url         string    `json:"url"`
+	SHA256      string    `json:"sha256"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type,omitempty"`
+	HTTPStatus  int       `json:"http_status"`
+	Fetched     time.Time `json:"fetched"`
+}
+
+// openDocCache loads the index from dir. The index is append-only
+// JSONL, so later lines win and a crash can't lose earlier entries.
+func openDocCache(dir string) (*docCache, error) {
+	c := &docCache{dir: dir, index: make(map[string]docIndexEntry)}
+	if err := os.MkdirAll(filepath.Join(dir, "objects"), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("unable to create document cache: %w", err)
+	}
+
+	f, err := os.Open(c.indexFile())
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to open document cache index: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry docIndexEntry
+		// skip anything half-written
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		c.index[entry.Url] = entry
+	}
+
+	return c, scanner.Err()
+}
+
+func (c *docCache) indexFile() string {
+	return filepath.Join(c.dir, "index.jsonl")
+}
+
+func (c *docCache) objectFile(hash string) string {
+	return filepath.Join(c.dir, "objects", hash[:2], hash)
+}
+
+// get returns the cached copy of url, if we have one
+func (c *docCache) get(docUrl string) (docIndexEntry, []byte, bool) {
+	if c == nil {
+		return docIndexEntry{}, nil, false
+	}
+	c.mu.Lock()
+	entry, ok := c.index[docUrl]
+	c.mu.Unlock()
+	if !ok {
+		return entry, nil, false
+	}
+
+	buf, err := os.ReadFile(c.objectFile(entry.SHA256))
+	if err != nil {
+		return entry, nil, false
+	}
+	return entry, buf, true
+}
+
+// put stores a fetched document and indexes it under its URL. The same
+// document from several URLs is only stored once.
+func (c *docCache) put(entry docIndexEntry, buf []byte) error {
+	if c == nil {
+		return nil
+	}
+
+	objectFile := c.objectFile(entry.SHA256)
+	if _, err := os.Stat(objectFile); err != nil {
+		if err := os.MkdirAll(filepath.Dir(objectFile), os.ModePerm); err != nil {
+			return fmt.Errorf("unable to cache %s: %w", entry.Url, err)
+		}
+		// write to a temp file of our own and rename it into place, so
+		// two workers with the same document can't trip over each other
+		tmp, err := os.CreateTemp(filepath.Dir(objectFile), entry.SHA256+".*.tmp")
+		if err != nil {
+			return fmt.Errorf("unable to cache %s: %w", entry.Url, err)
+		}
+		_, err = tmp.Write(buf)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), objectFile)
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+			return fmt.Errorf("unable to cache %s: %w", entry.Url, err)
+		}
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("unable to index %s: %w", entry.Url, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, err := os.OpenFile(c.indexFile(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to index %s: %w", entry.Url, err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("unable to index %s: %w", entry.Url, err)
+	}
+	c.index[entry.Url] = entry
+
+	return nil
+}
+
+// urls returns the cached documents for domain (and its subdomains)
+// with one of the given extensions, for offline re-analysis
+func (c *docCache) urls(domain string, extensions []string) []dorkResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	domain = strings.ToLower(domain)
+	var results []dorkResult
+	for docUrl := range c.index {
+		u, err := url.Parse(docUrl)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+		extension := strings.TrimPrefix(strings.ToLower(path.Ext(u.Path)), ".")
+		if !slices.Contains(extensions, extension) {
+			continue
+		}
+		results = append(results, dorkResult{searchEngine: "cache", url: docUrl})
+	}
+
+	// map order is random, and nobody wants that
+	slices.SortFunc(results, func(a, b dorkResult) int { return strings.Compare(a.url, b.url) })
+	return results
+}
+
+// docHashes makes sure identical documents only get analysed once per
+// run, however many URLs they turn up at
+type docHashes struct {
+	mu      sync.Mutex
+	entries map[string]*hashEntry
+}
+
+type hashEntry struct {
+	done   chan empty
+	result analysisResult
+}
+
+func newDocHashes() *docHashes {
+	return &docHashes{entries: make(map[string]*hashEntry)}
+}
+
+// claim returns the entry for hash, and whether we're the first to see
+// it. The first caller has to call finish once it's analysed the
+// document; everyone else waits on it.
+func (h *docHashes) claim(hash string) (*hashEntry, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if entry, ok := h.entries[hash]; ok {
+		return entry, false
+	}
+	entry := &hashEntry{done: make(chan empty)}
+	h.entries[hash] = entry
+	return entry, true
+}
+
+// seed records a document analysed by an earlier run or scan
+func (h *docHashes) seed(result analysisResult) {
+	if result.sha256 == "" || result.status == statusDuplicate {
+		return
+	}
+	if entry, first := h.claim(result.sha256); first {
+		entry.finish(result)
+	}
+}
+
+// finish hands the analysis to anyone waiting on the same document
+func (e *hashEntry) finish(result analysisResult) {
+	e.result = result
+	close(e.done)
+}
+
+// wait blocks until the first copy of the document has been analysed
+func (e *hashEntry) wait() analysisResult {
+	<-e.done
+	return e.result
+}
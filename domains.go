@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var (
+	// CORP\jsmith - only when it stands on its own, not mid-path
+	downLevelLogonRegex = regexp.MustCompile(`(?:^|[\s"'(])([A-Z][A-Z0-9-]{1,14})\\([A-Za-z][A-Za-z0-9._-]{1,19})(?:$|[\s"')])`)
+	// C:\Users\jsmith.CORP\ - Windows adds the domain when a local
+	// profile with the same name already exists
+	profileSuffixRegex = regexp.MustCompile(`(?i)(?:Users|Documents and Settings)[\\/][^\\/.]+\.([A-Za-z][A-Za-z0-9-]{1,14})[\\/]`)
+	// \\CORP\dfs\..., \\corp.example.com\SYSVOL\... - domain-based
+	// namespaces and DC shares are rooted at the domain name
+	domainShareRegex = regexp.MustCompile(`(?i)\\\\([A-Za-z0-9.-]+)\\(dfs|dfsroot|sysvol|netlogon|shares?|data|departments?)\\`)
+	// \\fs01.corp.example.com\ - the host tells us the DNS domain
+	fqdnUncRegex = regexp.MustCompile(`\\\\([A-Za-z0-9-]+\.[A-Za-z0-9.-]+)\\`)
+)
+
+// things that look like down-level logons but aren't
+var notNetbiosDomains = []string{
+	"NT AUTHORITY",
+	"BUILTIN",
+	"SYSTEM",
+	"HKLM",
+	"HKCU",
+	"HKEY_LOCAL_MACHINE",
+	"WINDOWS",
+	"USERS",
+}
+
+// free webmail providers say nothing about the target's AD domain
+var freemailDomains = []string{
+	"gmail.com",
+	"googlemail.com",
+	"outlook.com",
+	"hotmail.com",
+	"hotmail.co.uk",
+	"live.com",
+	"msn.com",
+	"yahoo.com",
+	"yahoo.co.uk",
+	"aol.com",
+	"icloud.com",
+	"me.com",
+	"mac.com",
+	"protonmail.com",
+	"proton.me",
+	"gmx.com",
+	"gmx.net",
+	"mail.com",
+	"zoho.com",
+	"yandex.com",
+}
+
+// evidence weights. the more explicit the mention of a domain, the
+// higher the score.
+const (
+	weightDownLevelLogon = 2.0
+	weightProfileSuffix  = 2.0
+	weightDomainShare    = 2.0
+	weightFqdnHost       = 1.5
+	weightInternalDomain = 1.0
+	weightUpnEmail       = 1.0
+	weightEmail          = 0.5
+)
+
+// inferDomains takes a pass over the final results and works out the
+// likely AD domain(s), with the evidence for each.
+func inferDomains(results FinalResult) []DomainCandidate {
+	candidates := make(map[string]*DomainCandidate)
+
+	// each bit of evidence, and the documents it turned up in
+	type evidence struct {
+		kind, value string
+		fileNames   []string
+	}
+	evidenceFor := make(map[string][]*evidence)
+
+	// addEvidence records evidence against the NetBIOS name, along
+	// with the DNS name if we have one.
+	addEvidence := func(netbios, dnsName string, weight float64, kind, value, fileName string) {
+		netbios = strings.ToUpper(netbios)
+		dnsName = strings.ToLower(strings.Trim(dnsName, "."))
+		if netbios == "" || slices.Contains(notNetbiosDomains, netbios) {
+			return
+		}
+
+		c, ok := candidates[netbios]
+		if !ok {
+			c = &DomainCandidate{NetBIOS: netbios}
+			candidates[netbios] = c
+		}
+		if dnsName != "" && !slices.Contains(c.DNSNames, dnsName) {
+			c.DNSNames = append(c.DNSNames, dnsName)
+		}
+		// only score each unique bit of evidence once, however many
+		// documents it's in, otherwise one template used a hundred
+		// times drowns everything else out
+		i := slices.IndexFunc(evidenceFor[netbios], func(e *evidence) bool { return e.kind == kind && e.value == value })
+		if i == -1 {
+			evidenceFor[netbios] = append(evidenceFor[netbios], &evidence{kind: kind, value: value})
+			i = len(evidenceFor[netbios]) - 1
+			c.Score += weight
+		}
+		if e := evidenceFor[netbios][i]; !slices.Contains(e.fileNames, fileName) {
+			e.fileNames = append(e.fileNames, fileName)
+		}
+	}
+
+	// everything that might contain a path or logon name
+	type source struct{ value, fileName string }
+	var sources []source
+	for _, v := range results.FilePaths {
+		sources = append(sources, source{v.FilePath, v.FileName})
+	}
+	for _, v := range results.LastSavedPaths {
+		sources = append(sources, source{v.Path, v.FileName})
+	}
+	for _, v := range results.ImageLinks {
+		sources = append(sources, source{v.ImageLink, v.FileName})
+	}
+	for _, v := range results.ExternalLinks {
+		sources = append(sources, source{v.ExternalLink, v.FileName})
+	}
+	for _, v := range results.Names {
+		sources = append(sources, source{v.Name, v.FileName})
+	}
+	for _, v := range results.Usernames {
+		sources = append(sources, source{v.UserName, v.FileName})
+	}
+	for _, v := range results.GreppedValues {
+		sources = append(sources, source{v.Value, v.FileName})
+	}
+
+	for _, src := range sources {
+		var shareRoots []string
+
+		for _, match := range downLevelLogonRegex.FindAllStringSubmatch(src.value, -1) {
+			addEvidence(match[1], "", weightDownLevelLogon, "down-level logon", strings.TrimSpace(match[0]), src.fileName)
+		}
+
+		for _, match := range profileSuffixRegex.FindAllStringSubmatch(src.value, -1) {
+			addEvidence(match[1], "", weightProfileSuffix, "profile path", match[0], src.fileName)
+		}
+
+		for _, match := range domainShareRegex.FindAllStringSubmatch(src.value, -1) {
+			root := match[1]
+			shareRoots = append(shareRoots, strings.ToLower(root))
+			if strings.Contains(root, ".") {
+				// domain-based namespace by DNS name
+				addEvidence(netbiosFromDns(root), root, weightDomainShare, "domain share", match[0], src.fileName)
+			} else {
+				addEvidence(root, "", weightDomainShare, "domain share", match[0], src.fileName)
+			}
+		}
+
+		for _, match := range fqdnUncRegex.FindAllStringSubmatch(src.value, -1) {
+			// \\corp.example.com\sysvol is the domain, not a host in it
+			if slices.Contains(shareRoots, strings.ToLower(match[1])) {
+				continue
+			}
+			if dnsName := parentDomain(match[1]); dnsName != "" {
+				addEvidence(netbiosFromDns(dnsName), dnsName, weightFqdnHost, "UNC host", match[1], src.fileName)
+			}
+		}
+	}
+
+	// internal domain names from network intel. AD domains are the
+	// domain already, internal FQDNs have a host on the front.
+	for _, intel := range results.NetworkIntel {
+		var dnsName string
+		switch intel.Type {
+		case "ad-domain":
+			dnsName = strings.ToLower(intel.Value)
+		case "internal-fqdn":
+			dnsName = parentDomain(intel.Value)
+		}
+		if dnsName != "" {
+			addEvidence(netbiosFromDns(dnsName), dnsName, weightInternalDomain, "internal name", intel.Value, intel.FileName)
+		}
+	}
+
+	// email domains. UPN suffixes tend to be sub-domains, eg.
+	// jsmith@corp.example.com, which is a better hint than a plain
+	// example.com email address.
+	for _, email := range results.Emails {
+		_, domain, found := strings.Cut(email.EmailAddr, "@")
+		if !found || !strings.Contains(domain, ".") {
+			continue
+		}
+		if slices.Contains(freemailDomains, strings.ToLower(domain)) {
+			continue
+		}
+		if strings.Count(domain, ".") >= 2 {
+			addEvidence(netbiosFromDns(domain), domain, weightUpnEmail, "UPN-style email", email.EmailAddr, email.FileName)
+		} else {
+			addEvidence(netbiosFromDns(domain), domain, weightEmail, "email", email.EmailAddr, email.FileName)
+		}
+	}
+
+	var r []DomainCandidate
+	for netbios, c := range candidates {
+		for _, e := range evidenceFor[netbios] {
+			c.Evidence = append(c.Evidence, fmt.Sprintf("%s \"%s\" in %s", e.kind, e.value, quoteFileNames(e.fileNames)))
+		}
+		switch {
+		case c.Score >= 3:
+			c.Confidence = "high"
+		case c.Score >= 1.5:
+			c.Confidence = "medium"
+		default:
+			c.Confidence = "low"
+		}
+		r = append(r, *c)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].Score != r[j].Score {
+			return r[i].Score > r[j].Score
+		}
+		return r[i].NetBIOS < r[j].NetBIOS
+	})
+
+	return r
+}
+
+// parentDomain strips the host from an FQDN. Returns "" if
+// there isn't enough left to be a domain, eg. fs01.local.
+func parentDomain(fqdn string) string {
+	labels := strings.Split(strings.Trim(fqdn, "."), ".")
+	if len(labels) < 3 {
+		return ""
+	}
+	return strings.ToLower(strings.Join(labels[1:], "."))
+}
+
+// netbiosFromDns guesses the NetBIOS name from a DNS domain. By
+// default it's the left-most label, truncated to 15 characters.
+func netbiosFromDns(dnsName string) string {
+	label, _, _ := strings.Cut(dnsName, ".")
+	if len(label) > 15 {
+		label = label[:15]
+	}
+	return strings.ToUpper(label)
+}
+
+// quoteFileNames lists the first few documents some evidence was found
+// in, and how many more there were
+func quoteFileNames(fileNames []string) string {
+	const shown = 3
+	var quoted []string
+	for _, fileName := range fileNames[:min(len(fileNames), shown)] {
+		quoted = append(quoted, fmt.Sprintf("\"%s\"", fileName))
+	}
+	list := strings.Join(quoted, ", ")
+	if len(fileNames) > shown {
+		list += fmt.Sprintf(" and %d more", len(fileNames)-shown)
+	}
+	return list
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestInferDomainsNetworkIntel(t *testing.T) {
+	tests := []struct {
+		name        string
+		intel       NetworkIntel
+		wantNetbios string
+		wantDns     string
+	}{
+		{
+			name:        "ad domain is used as is",
+			intel:       NetworkIntel{Type: "ad-domain", Value: "corp.example.com"},
+			wantNetbios: "CORP",
+			wantDns:     "corp.example.com",
+		},
+		{
+			name:        "internal fqdn loses its host",
+			intel:       NetworkIntel{Type: "internal-fqdn", Value: "fs01.corp.local"},
+			wantNetbios: "CORP",
+			wantDns:     "corp.local",
+		},
+		{
+			name:  "two label internal fqdn is just a host",
+			intel: NetworkIntel{Type: "internal-fqdn", Value: "fs01.local"},
+		},
+		{
+			name:  "other intel is ignored",
+			intel: NetworkIntel{Type: "private-ipv4", Value: "10.0.0.1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.intel.FileName = "a.docx"
+			got := inferDomains(FinalResult{NetworkIntel: []NetworkIntel{tt.intel}})
+			if tt.wantNetbios == "" {
+				if len(got) != 0 {
+					t.Fatalf("inferDomains() = %+v, want nothing", got)
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("inferDomains() = %+v, want one candidate", got)
+			}
+			if got[0].NetBIOS != tt.wantNetbios {
+				t.Errorf("NetBIOS = %q, want %q", got[0].NetBIOS, tt.wantNetbios)
+			}
+			if !slices.Equal(got[0].DNSNames, []string{tt.wantDns}) {
+				t.Errorf("DNSNames = %v, want [%s]", got[0].DNSNames, tt.wantDns)
+			}
+		})
+	}
+}
+
+func TestParentDomain(t *testing.T) {
+	tests := map[string]string{
+		"fs01.corp.example.com": "corp.example.com",
+		"FS01.Corp.Local.":      "corp.local",
+		"fs01.local":            "",
+		"intranet":              "",
+	}
+	for fqdn, want := range tests {
+		if got := parentDomain(fqdn); got != want {
+			t.Errorf("parentDomain(%q) = %q, want %q", fqdn, got, want)
+		}
+	}
+}
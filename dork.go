@@ -2,75 +2,97 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/redskal/dragonvomit/pkg/bing"
 	customsearch "google.golang.org/api/customsearch/v1"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 )
 
-// googleDork dorks through Google and adds URLs to a channel
-func googleDork(apiKey, customSearchId, domain, fileType string, urls chan dorkResult, tracker chan empty) error {
-	ctx := context.Background()
-	customsearchService, err := customsearch.NewService(ctx, option.WithAPIKey(apiKey))
-	if err != nil {
-		var e empty
-		tracker <- e
-		return err
-	}
+// errNoMatches means the dork worked, there just wasn't anything to find
+var errNoMatches = errors.New("no matches found")
 
+// googleDork dorks through Google and adds URLs to a channel
+func googleDork(ctx context.Context, client *http.Client, cache *searchCache, apiKey, customSearchId, domain, fileType string, urls chan dorkResult) error {
 	searchQuery := fmt.Sprintf("site:%s & filetype:%s", domain, fileType)
 
-	resp, err := customsearchService.Cse.List().Cx(customSearchId).Q(searchQuery).Do()
-	if err != nil {
-		var e empty
-		tracker <- e
-		return err
+	results, ok := cache.get("google", searchQuery, 0)
+	if !ok {
+		// our own client overrides option.WithAPIKey, so the key goes
+		// on the query string instead
+		customsearchService, err := customsearch.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			return fmt.Errorf("[google] %w", err)
+		}
+
+		var resp *customsearch.Search
+		err = retries.do(ctx, "Google search for "+fileType, func() error {
+			var err error
+			resp, err = customsearchService.Cse.List().Cx(customSearchId).Q(searchQuery).Context(ctx).Do(googleapi.QueryParameter("key", apiKey))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("[google] %w", err)
+		}
+
+		for _, result := range resp.Items {
+			results = append(results, dorkResult{
+				searchEngine: "google",
+				url:          result.Link,
+			})
+		}
+		cache.put("google", searchQuery, 0, results)
 	}
 
-	if len(resp.Items) == 0 {
-		var e empty
-		tracker <- e
-		return err
+	if len(results) == 0 {
+		return fmt.Errorf("[google] %w", errNoMatches)
 	}
 
-	for _, result := range resp.Items {
-		urls <- dorkResult{
-			searchEngine: "google",
-			url:          result.Link,
-		}
+	for _, result := range results {
+		urls <- result
 	}
 
-	var e empty
-	tracker <- e
 	return nil
 }
 
 // bingDork dorks through Bing and adds URLs to a channel
-func bingDork(apiKey, domain, fileType string, urls chan dorkResult, tracker chan empty) error {
-	bingClient := bing.NewClient(apiKey)
+func bingDork(ctx context.Context, client *http.Client, cache *searchCache, apiKey, domain, fileType string, urls chan dorkResult) error {
 	searchQuery := fmt.Sprintf("site:%s && filetype:%s && instreamset:(url title):%s", domain, fileType, fileType)
-	resp, err := bingClient.Search(searchQuery)
-	if err != nil {
-		var e empty
-		tracker <- e
-		return err
+
+	results, ok := cache.get("bing", searchQuery, 0)
+	if !ok {
+		bingClient := bing.NewClient(apiKey, client)
+		var resp *bing.BingAnswer
+		err := retries.do(ctx, "Bing search for "+fileType, func() error {
+			var err error
+			resp, err = bingClient.SearchContext(ctx, searchQuery)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("[bing] %w", err)
+		}
+
+		for _, result := range resp.WebPages.Value {
+			results = append(results, dorkResult{
+				searchEngine: "bing",
+				url:          result.URL,
+				crawled:      result.DateLastCrawled,
+			})
+		}
+		cache.put("bing", searchQuery, 0, results)
 	}
 
-	if resp.WebPages.TotalEstimatedMatches == 0 {
-		var e empty
-		tracker <- e
-		return fmt.Errorf("[bing] no matches found")
+	if len(results) == 0 {
+		return fmt.Errorf("[bing] %w", errNoMatches)
 	}
+
 	// write URLs to our output channel
-	for _, result := range resp.WebPages.Value {
-		urls <- dorkResult{
-			searchEngine: "bing",
-			url:          result.URL,
-		}
+	for _, result := range results {
+		urls <- result
 	}
 
-	var e empty
-	tracker <- e
 	return nil
 }
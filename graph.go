@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/redskal/dragonvomit/pkg/software"
+)
+
+// \\host\share from any UNC path
+var uncShareRegex = regexp.MustCompile(`\\\\([A-Za-z0-9.$-]+)\\([^\\/:*?"<>|\r\n]+)`)
+
+// Graph links the things we found to each other and to the documents
+// they were found in.
+type Graph struct {
+	Nodes []GraphNode
+	Edges []GraphEdge
+	// node IDs already added, so we don't double up
+	ids map[string]bool
+	// edges already added
+	edges map[GraphEdge]bool
+}
+
+type GraphNode struct {
+	ID    string
+	Type  string // Document, Person, Username, Email, Host, Share, Software
+	Label string
+}
+
+type GraphEdge struct {
+	Source string
+	Target string
+	Type   string
+}
+
+// addNode adds a node if we've not got it, and returns its ID
+func (g *Graph) addNode(nodeType, key, label string) string {
+	id := nodeType + ":" + strings.ToLower(key)
+	if !g.ids[id] {
+		g.ids[id] = true
+		g.Nodes = append(g.Nodes, GraphNode{ID: id, Type: nodeType, Label: label})
+	}
+	return id
+}
+
+// addEdge adds an edge if we've not got it
+func (g *Graph) addEdge(source, target, edgeType string) {
+	e := GraphEdge{Source: source, Target: target, Type: edgeType}
+	if !g.edges[e] {
+		g.edges[e] = true
+		g.Edges = append(g.Edges, e)
+	}
+}
+
+// buildGraph turns the flat per-category results into a graph of
+// documents, people, usernames, emails, hosts, shares and software.
+func buildGraph(results FinalResult) *Graph {
+	g := &Graph{ids: make(map[string]bool), edges: make(map[GraphEdge]bool)}
+
+	doc := func(fileName, fileUrl string) string {
+		return g.addNode("Document", fileUrl, fileName)
+	}
+
+	// people, and what's theirs
+	personByHandle := make(map[string]string)
+	for _, person := range results.People {
+		label := personLabel(person)
+		id := g.addNode("Person", label, label)
+		if person.Name != "" {
+			personByHandle[strings.ToLower(person.Name)] = id
+		}
+		for _, username := range person.Usernames {
+			g.addEdge(id, g.addNode("Username", username, username), "HAS_USERNAME")
+			personByHandle[username] = id
+		}
+		for _, email := range person.Emails {
+			g.addEdge(id, g.addNode("Email", email, email), "HAS_EMAIL")
+			personByHandle[email] = id
+		}
+	}
+
+	// who wrote what, from the document dates
+	for _, e := range results.Timeline {
+		if e.Person == "" {
+			continue
+		}
+		personId, ok := personByHandle[strings.ToLower(normaliseName(e.Person))]
+		if !ok {
+			personId, ok = personByHandle[strings.ToLower(e.Person)]
+		}
+		if !ok {
+			continue
+		}
+		switch e.Event {
+		case "created":
+			g.addEdge(personId, doc(e.FileName, e.FileUrl), "AUTHORED")
+		case "modified":
+			g.addEdge(personId, doc(e.FileName, e.FileUrl), "MODIFIED")
+		}
+	}
+
+	// findings in each document
+	for _, u := range results.Usernames {
+		g.addEdge(doc(u.FileName, u.FileUrl), g.addNode("Username", strings.ToLower(u.UserName), u.UserName), "MENTIONS")
+	}
+	for _, e := range results.Emails {
+		g.addEdge(doc(e.FileName, e.FileUrl), g.addNode("Email", strings.ToLower(e.EmailAddr), e.EmailAddr), "MENTIONS")
+	}
+	for _, n := range results.Names {
+		if id, ok := personByHandle[strings.ToLower(normaliseName(n.Name))]; ok {
+			g.addEdge(doc(n.FileName, n.FileUrl), id, "MENTIONS")
+		}
+	}
+	for _, h := range results.Hostnames {
+		g.addEdge(doc(h.FileName, h.FileUrl), g.addNode("Host", h.Hostname, h.Hostname), "REFERENCES")
+	}
+
+	// shares from UNC paths, and the hosts they live on
+	type pathSource struct{ path, fileName, fileUrl string }
+	var paths []pathSource
+	for _, p := range results.FilePaths {
+		paths = append(paths, pathSource{p.FilePath, p.FileName, p.FileUrl})
+	}
+	for _, p := range results.LastSavedPaths {
+		paths = append(paths, pathSource{p.Path, p.FileName, p.FileUrl})
+	}
+	for _, p := range results.ImageLinks {
+		paths = append(paths, pathSource{p.ImageLink, p.FileName, p.FileUrl})
+	}
+	for _, p := range results.ExternalLinks {
+		paths = append(paths, pathSource{p.ExternalLink, p.FileName, p.FileUrl})
+	}
+	for _, p := range paths {
+		for _, match := range uncShareRegex.FindAllStringSubmatch(p.path, -1) {
+			share := fmt.Sprintf(`\\%s\%s`, match[1], match[2])
+			shareId := g.addNode("Share", share, share)
+			g.addEdge(doc(p.fileName, p.fileUrl), shareId, "LINKS_TO")
+			g.addEdge(shareId, g.addNode("Host", match[1], match[1]), "HOSTED_ON")
+		}
+	}
+
+	// software that made each document
+	for _, s := range results.Softwares {
+		info := software.Parse(s.Value)
+		label := strings.TrimSpace(strings.Join([]string{info.Vendor, info.Product, info.Version}, " "))
+		g.addEdge(doc(s.FileName, s.FileUrl), g.addNode("Software", label, label), "CREATED_WITH")
+	}
+
+	return g
+}
+
+// graphml is just enough of the GraphML schema for nodes with a type
+// and label, and typed edges
+type graphml struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphmlKey `xml:"key"`
+	Graph   graphmlGraph `xml:"graph"`
+}
+
+type graphmlKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphmlGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphmlNode `xml:"node"`
+	Edges       []graphmlEdge `xml:"edge"`
+}
+
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphmlNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphmlData `xml:"data"`
+}
+
+type graphmlEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphmlData `xml:"data"`
+}
+
+// writeGraphML writes the graph to fileName as GraphML, which opens
+// in Gephi, yEd, Cytoscape, etc.
+func writeGraphML(g *Graph, fileName string) error {
+	doc := graphml{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphmlKey{
+			{ID: "type", For: "node", AttrName: "type", AttrType: "string"},
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "relationship", For: "edge", AttrName: "relationship", AttrType: "string"},
+		},
+		Graph: graphmlGraph{ID: "dragonvomit", EdgeDefault: "directed"},
+	}
+	for _, n := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphmlNode{
+			ID:   n.ID,
+			Data: []graphmlData{{Key: "type", Value: n.Type}, {Key: "label", Value: n.Label}},
+		})
+	}
+	for _, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphmlEdge{
+			Source: e.Source,
+			Target: e.Target,
+			Data:   []graphmlData{{Key: "relationship", Value: e.Type}},
+		})
+	}
+
+	content, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal graph to GraphML: %w", err)
+	}
+	if err := os.WriteFile(fileName, append([]byte(xml.Header), content...), 0644); err != nil {
+		return fmt.Errorf("error writing GraphML to file: %w", err)
+	}
+
+	return nil
+}
+
+// writeNeo4jCsv writes nodes.csv and relationships.csv to dir, in the
+// format neo4j-admin import expects.
+func writeNeo4jCsv(g *Graph, dir string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create Neo4j output directory: %w", err)
+	}
+
+	nodeRows := [][]string{{"id:ID", "name", ":LABEL"}}
+	for _, n := range g.Nodes {
+		nodeRows = append(nodeRows, []string{n.ID, n.Label, n.Type})
+	}
+	if err := writeCsvFile(filepath.Join(dir, "nodes.csv"), nodeRows); err != nil {
+		return err
+	}
+
+	edgeRows := [][]string{{":START_ID", ":END_ID", ":TYPE"}}
+	for _, e := range g.Edges {
+		edgeRows = append(edgeRows, []string{e.Source, e.Target, e.Type})
+	}
+	return writeCsvFile(filepath.Join(dir, "relationships.csv"), edgeRows)
+}
+
+// writeCsvFile writes rows to fileName as CSV
+func writeCsvFile(fileName string, rows [][]string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %w", fileName, err)
+	}
+
+	w := csv.NewWriter(f)
+	if err := w.WriteAll(rows); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing %s: %w", fileName, err)
+	}
+	// a full disk may only show up when the file is closed
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error writing %s: %w", fileName, err)
+	}
+
+	return nil
+}
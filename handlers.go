@@ -3,15 +3,30 @@ package main
 import (
 	"archive/zip"
 	"bytes"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/redskal/dragonvomit/pkg/metadataplus"
+	"github.com/redskal/dragonvomit/pkg/netintel"
+	"github.com/redskal/dragonvomit/pkg/secrets"
 	"seehuhn.de/go/pdf"
 )
 
+// pdfMetadata holds everything we care about from a PDF
+type pdfMetadata struct {
+	Author   string
+	Created  time.Time
+	Modified time.Time
+	Software []string
+	Secrets  []secrets.Finding
+	Intel    []netintel.Finding
+}
+
 // pdfParse will grab metadata from a PDF to extract the
 // author as a potential user, and the software used to
 // create the PDF for intel.
-func pdfParse(pdfFile []byte) (string, []string, error) {
+func pdfParse(pdfFile []byte) (*pdfMetadata, error) {
 	// pdf.Read expects an io.ReadSeeker interface
 	// so we need to create a reader
 	r := bytes.NewReader(pdfFile)
@@ -19,26 +34,34 @@ func pdfParse(pdfFile []byte) (string, []string, error) {
 	// load the PDF
 	pdfData, err := pdf.Read(r, nil)
 	if err != nil {
-		return "", []string{}, err
+		return nil, err
 	}
 
 	// load the metadata and check the author is there
 	meta := pdfData.GetMeta()
-	var author string
+	var result pdfMetadata
 	if meta.Info.Author != "" {
-		author = meta.Info.Author
+		result.Author = meta.Info.Author
 	}
 
+	// dates for the timeline
+	result.Created = meta.Info.CreationDate
+	result.Modified = meta.Info.ModDate
+
 	// record the software if it's available
-	var software []string
 	if meta.Info.Creator != "" {
-		software = append(software, meta.Info.Creator)
+		result.Software = append(result.Software, meta.Info.Creator)
 	}
 	if meta.Info.Producer != "" {
-		software = append(software, meta.Info.Producer)
+		result.Software = append(result.Software, meta.Info.Producer)
 	}
 
-	return author, software, nil
+	// people do paste all sorts into the title, subject and keywords
+	infoText := strings.Join([]string{meta.Info.Title, meta.Info.Subject, meta.Info.Keywords}, "\n")
+	result.Secrets = secrets.Find(infoText)
+	result.Intel = netintel.Find(infoText)
+
+	return &result, nil
 }
 
 func officeParse(officeFile []byte) (*metadataplus.MetaData, error) {
@@ -54,3 +77,22 @@ func officeParse(officeFile []byte) (*metadataplus.MetaData, error) {
 
 	return metadata, nil
 }
+
+// parseW3CDTF parses the timestamps in Office core properties. They're
+// meant to be W3CDTF, but some generators leave off the timezone.
+func parseW3CDTF(s string) (time.Time, error) {
+	layouts := []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02",
+	}
+	s = strings.TrimSpace(s)
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognised date: %s", s)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/redskal/dragonvomit/pkg/software"
+)
+
+// buildSoftwareInventory normalises the raw software strings and
+// counts how many documents each product/version turns up in.
+func buildSoftwareInventory(results FinalResult) []SoftwareItem {
+	items := make(map[software.Info]*SoftwareItem)
+	// documents already counted for each item
+	seen := make(map[software.Info][]string)
+
+	for _, s := range results.Softwares {
+		info := software.Parse(s.Value)
+		raw := info.Raw
+		// group on everything but the raw string
+		info.Raw = ""
+
+		item, ok := items[info]
+		if !ok {
+			item = &SoftwareItem{
+				Vendor:  info.Vendor,
+				Product: info.Product,
+				Version: info.Version,
+				OS:      info.OS,
+			}
+			item.EOLDate, item.EOL = info.EndOfLife()
+			items[info] = item
+		}
+		if !slices.Contains(item.Raw, raw) {
+			item.Raw = append(item.Raw, raw)
+		}
+		if !slices.Contains(seen[info], s.FileUrl) {
+			seen[info] = append(seen[info], s.FileUrl)
+			item.Count++
+		}
+	}
+
+	var r []SoftwareItem
+	for _, item := range items {
+		r = append(r, *item)
+	}
+	// most common first, EOL stuff on top of that
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].EOL != r[j].EOL {
+			return r[i].EOL
+		}
+		if r[i].Count != r[j].Count {
+			return r[i].Count > r[j].Count
+		}
+		if r[i].Product != r[j].Product {
+			return r[i].Product < r[j].Product
+		}
+		return r[i].Version < r[j].Version
+	})
+
+	return r
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// jsonlRecord is one line of JSONL output
+type jsonlRecord struct {
+	Type         string    `json:"type"`
+	Subtype      string    `json:"subtype,omitempty"`
+	Value        string    `json:"value,omitempty"`
+	Document     string    `json:"document,omitempty"`
+	Url          string    `json:"url"`
+	SearchEngine string    `json:"search_engine,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// jsonlWriter streams findings as newline-delimited JSON, one object
+// per line, as each document is analysed
+type jsonlWriter struct {
+	mu  sync.Mutex
+	out io.WriteCloser
+	enc *json.Encoder
+}
+
+// newJsonlWriter writes to fileName, or stdout if it's "-"
+func newJsonlWriter(fileName string) (*jsonlWriter, error) {
+	var out io.WriteCloser = os.Stdout
+	if fileName != "-" {
+		f, err := os.Create(fileName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create %s: %w", fileName, err)
+		}
+		out = f
+	}
+	return &jsonlWriter{out: out, enc: json.NewEncoder(out)}, nil
+}
+
+// writeResult writes a line for the document itself, then one for
+// each finding in it. Copies of another document just get a line
+// saying so, and documents we couldn't analyse are left out.
+func (w *jsonlWriter) writeResult(result analysisResult) error {
+	if result.status != statusAnalysed && result.status != statusDuplicate {
+		return nil
+	}
+
+	timestamp := result.fetched
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	records := []jsonlRecord{{
+		Type:         "documents",
+		Subtype:      result.fileType,
+		Document:     docFileName(result.url),
+		Url:          result.url,
+		SearchEngine: result.searchEngine,
+		Timestamp:    timestamp,
+	}}
+	if result.status == statusDuplicate {
+		records[0].Type = "duplicates"
+		records[0].Value = result.duplicateOf
+	}
+	for _, f := range resultFindings(result) {
+		records = append(records, jsonlRecord{
+			Type:         f.Type,
+			Subtype:      f.Subtype,
+			Value:        f.Value,
+			Document:     f.FileName,
+			Url:          f.FileUrl,
+			SearchEngine: result.searchEngine,
+			Timestamp:    timestamp,
+		})
+	}
+
+	for _, record := range records {
+		if err := w.enc.Encode(record); err != nil {
+			return fmt.Errorf("error writing JSONL: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close closes the output, unless it's stdout
+func (w *jsonlWriter) Close() error {
+	if w.out == os.Stdout {
+		return nil
+	}
+	return w.out.Close()
+}
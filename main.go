@@ -6,28 +6,38 @@
  * inspects it for interesting metadata that may be useful for social
  * engineering and/or red team engagements.
  *
- * Everything is done in-memory, so you'll need to download more RAM for
- * the more bountiful targets.
+ * Documents are analysed in-memory, so you'll need to download more RAM
+ * for the more bountiful targets. They're only written to disk if you
+ * ask for it with -cache or -save.
  */
 package main
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"slices"
 	"strings"
+	"sync"
+	"syscall"
 	"text/tabwriter"
+	"time"
 
+	"github.com/redskal/dragonvomit/pkg/httpclient"
+	"github.com/redskal/dragonvomit/pkg/secrets"
 	"github.com/redskal/dragonvomit/pkg/settings"
+	"github.com/redskal/dragonvomit/pkg/store"
 )
 
 var (
@@ -57,32 +67,136 @@ var (
         -config <string>    Set your API keys, etc. "bing" = Bing key, "googleKey" = Google API key,
                             "googleId" = Google Custom Search Engine ID
         -search <domain>    The domain to dork against
+        -resume <scan-id>   Resume an interrupted scan. Finished dorks and documents are skipped, so
+                            neither API queries nor bandwidth are spent twice.
         -extensions <list>  Comma-separated list of file types to dork for
                             Currently supports (and dorks by default):
                             xlsx, xlsm, xltx, xltm, docx, docm, dotm, dotx, ppt, pptx, potm, potx, pdf
         -threads <int>      Number of threads to use for downloading and analysing documents. [default = 50]
+        -rate <float>       Maximum document requests per second, across all hosts. [default = no limit]
+        -hostrate <float>   Maximum document requests per second to any one host. [default = no limit]
+        -hostthreads <int>  Maximum document requests in flight to any one host at once, whatever
+                            -threads is set to. [default = no limit]
+        -jitter <dur>       Wait a random extra time, up to this long, before each request, eg. 2s.
+        -proxy <url>        Send all requests through an http://, https:// or socks5:// proxy. For Tor,
+                            use socks5://127.0.0.1:9050.
+        -useragent <string> User-Agent to send. [default = a desktop Chrome]
+        -useragents <file>  Pick a random User-Agent from the named file, one per line, for each request.
+        -header <string>    Extra "Name: value" header to send when fetching documents. Can be repeated.
+        -cookie <string>    Cookies to send when fetching documents, eg. "session=abc; lang=en".
+        -insecure           Don't verify TLS certificates.
+        -connecttimeout <dur>
+                            How long to wait to connect, including the TLS handshake. [default = 10s]
+        -readtimeout <dur>  How long to wait for a server to send anything before giving up. [default = 30s]
+        -retries <int>      How many times to retry a document or search that fails with a timeout, dropped
+                            connection, 5xx or 429. Backs off exponentially between attempts, and honours
+                            Retry-After. [default = 3]
+        -maxbackoff <dur>   Longest to wait between retries, including for Retry-After. [default = 30s]
+        -cachettl <dur>     How long to reuse cached search results for, eg. 12h. 0 disables the cache.
+                            [default = 24h]
+        -refresh            Ignore cached search results and documents, and fetch them again.
+        -cache              Keep fetched documents in ~/.DragonVomit/cache/documents, so later runs can
+                            reuse them and -offline can re-analyse them. Off by default.
+        -offline            Re-analyse documents kept with -cache without touching the network.
+                            Previously analysed documents are analysed again.
+        -maxsize <int>      Largest document to fetch, in MB. [default = 0, no limit]
+        -save <dir>         Save each fetched document to the named directory, with a manifest.csv
+                            recording its URL, fetch time, HTTP status, size, SHA-256 and content type.
+                            Documents over -maxsize or that aren't the type they claim are not saved.
         -json <filename>    Export findings to the named file in JSON format.
+        -jsonl <file|->     Stream one JSON object per finding to the named file as each document is
+                            analysed. "-" writes to stdout instead of the results tables.
+        -project <file>     Keep documents, sources and findings in a per-engagement SQLite database.
+                            Runs accumulate, and documents an earlier run already dealt with are skipped.
+                            Only network errors, 5xx and 429 responses are tried again.
+        -userlist <file>    Write a username wordlist, built from the inferred username format, to the
+                            named file. Emails go to the same name with "-emails" appended.
+        -timeline <file>    Write a sorted timeline of document created/modified/printed/crawled dates.
+                            CSV if the file name ends in .csv, otherwise JSON.
+        -graphml <file>     Export a graph of documents, people, usernames, emails, hosts, shares and
+                            software as GraphML.
+        -neo4j <dir>        Export the same graph as nodes.csv and relationships.csv for neo4j-admin import.
+        -maltego <file>     Export people, emails, domains, hostnames, documents and URLs as a Maltego
+                            entity table (CSV) for Import Graph from Table.
+        -csv <dir>          Export one CSV per category (names.csv, emails.csv, ...) plus a flat
+                            findings.csv with a type column. Values that a spreadsheet would treat as
+                            formulas are prefixed with '.
+        -html <file>        Write a self-contained HTML report with a summary dashboard, sortable
+                            tables and a per-document breakdown. Secrets follow -redact.
+        -markdown <file>    Write a Markdown report with an executive summary, a table per finding
+                            category and an appendix of document sources. Secrets follow -redact.
+        -redact <mode>      How to display secrets on the console and in HTML/Markdown reports:
+                            none, partial or full. JSON and CSV exports are never redacted. [default = partial]
 
     WARNING: using the default extension list will deplete your Google API limit and rack up your Bing bill
              pretty quickly.
 `
-	silent bool = false
+	silent  bool  = false
+	offline bool  = false // documents only come from the cache
+	refresh bool  = false // ignore cached searches and documents
+	maxSize int64         // largest document we'll fetch, in bytes
+
+	docClient = http.DefaultClient // for fetching documents
+	retries   retryPolicy          // how hard to try when requests fail
 )
 
 func main() {
 	quietPtr := flag.Bool("silent", false, "Only show results")
 	configPtr := flag.String("config", "", "Set API keys")
 	searchPtr := flag.String("search", "", "Domain to search for")
+	resumePtr := flag.String("resume", "", "ID of an interrupted scan to resume")
 	extensionsPtr := flag.String("extensions", "xlsx,xlsm,xltx,xltm,docx,docm,dotm,dotx,ppt,pptx,potm,potx,pdf", "Comma-separated list of file extensions to dork")
 	jsonExportPtr := flag.String("json", "", "Export to the named JSON file.")
+	jsonlPtr := flag.String("jsonl", "", "Stream findings as JSONL to the named file, or - for stdout")
+	projectPtr := flag.String("project", "", "Keep results in the named SQLite project database")
 	threadCount := flag.Int("threads", 50, "Amount of threads to use for pulling and analysing documents")
+	ratePtr := flag.Float64("rate", 0, "Maximum document requests per second")
+	hostRatePtr := flag.Float64("hostrate", 0, "Maximum document requests per second to each host")
+	hostThreadsPtr := flag.Int("hostthreads", 0, "Maximum concurrent document requests to each host")
+	jitterPtr := flag.Duration("jitter", 0, "Random extra delay, up to this long, before each request")
+	proxyPtr := flag.String("proxy", "", "HTTP or SOCKS5 proxy URL")
+	userAgentPtr := flag.String("useragent", httpclient.DefaultUserAgent, "User-Agent to send")
+	userAgentsPtr := flag.String("useragents", "", "File of User-Agents to pick from at random")
+	var headers headerFlags
+	flag.Var(&headers, "header", "Extra \"Name: value\" header for document requests (repeatable)")
+	cookiePtr := flag.String("cookie", "", "Cookies to send with document requests")
+	insecurePtr := flag.Bool("insecure", false, "Skip TLS certificate verification")
+	connectTimeoutPtr := flag.Duration("connecttimeout", 10*time.Second, "Connection timeout")
+	readTimeoutPtr := flag.Duration("readtimeout", 30*time.Second, "Read timeout")
+	retriesPtr := flag.Int("retries", 3, "How many times to retry failed requests")
+	maxBackoffPtr := flag.Duration("maxbackoff", 30*time.Second, "Longest wait between retries")
+	cacheTtlPtr := flag.Duration("cachettl", 24*time.Hour, "How long to reuse cached search results for")
+	refreshPtr := flag.Bool("refresh", false, "Ignore cached search results and documents")
+	docCachePtr := flag.Bool("cache", false, "Keep fetched documents in the document cache")
+	offlinePtr := flag.Bool("offline", false, "Only analyse documents from the document cache")
+	savePtr := flag.String("save", "", "Save fetched documents and a manifest to the named directory")
+	maxSizePtr := flag.Int64("maxsize", 0, "Largest document to fetch, in MB")
+	redactPtr := flag.String("redact", "partial", "Redaction mode for secrets on the console and in reports")
+	userlistPtr := flag.String("userlist", "", "Write username and email lists to the named file")
+	timelinePtr := flag.String("timeline", "", "Write a document timeline to the named CSV or JSON file")
+	graphmlPtr := flag.String("graphml", "", "Export the entity graph to the named GraphML file")
+	neo4jPtr := flag.String("neo4j", "", "Export the entity graph as Neo4j CSV to the named directory")
+	maltegoPtr := flag.String("maltego", "", "Export a Maltego entity table to the named CSV file")
+	csvPtr := flag.String("csv", "", "Export each category as CSV to the named directory")
+	htmlPtr := flag.String("html", "", "Write an HTML report to the named file")
+	markdownPtr := flag.String("markdown", "", "Write a Markdown report to the named file")
 	flag.Usage = func() {
 		fmt.Print(usage)
 		os.Exit(0)
 	}
 	flag.Parse()
 
-	if *quietPtr {
+	if !slices.Contains([]string{"none", "partial", "full"}, *redactPtr) {
+		log.Fatalf("invalid -redact mode %q, expected none, partial or full", *redactPtr)
+	}
+
+	offline = *offlinePtr
+	refresh = *refreshPtr
+	maxSize = *maxSizePtr * 1024 * 1024
+	retries = retryPolicy{retries: *retriesPtr, maxBackoff: *maxBackoffPtr}
+
+	// keep stdout clean if we're streaming JSONL to it
+	if *quietPtr || *jsonlPtr == "-" {
 		silent = true
 	}
 
@@ -91,7 +205,7 @@ func main() {
 		fmt.Print(banner)
 	}
 
-	if *configPtr == "" && *searchPtr == "" {
+	if *configPtr == "" && *searchPtr == "" && *resumePtr == "" {
 		flag.Usage()
 		os.Exit(1)
 	}
@@ -121,7 +235,7 @@ func main() {
 		if err := settings.SetUserSettings(*configPtr, settingsFile); err != nil {
 			log.Fatal(err)
 		}
-		if *searchPtr == "" {
+		if *searchPtr == "" && *resumePtr == "" {
 			// no point going any further...
 			os.Exit(0)
 		}
@@ -138,43 +252,210 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// pick up where an earlier scan left off, or start a new one. the
+	// checkpoint knows what we were searching for.
+	scansDir := filepath.Join(dragonVomitDir, "scans")
+	var scan *scanCheckpoint
+	if *resumePtr != "" {
+		scan, err = loadScan(scansDir, *resumePtr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		*searchPtr = scan.state.Domain
+		*extensionsPtr = scan.state.Extensions
+	} else {
+		scan = newScan(scansDir, *searchPtr, *extensionsPtr)
+	}
+	if !silent {
+		fmt.Println("[i] Scan ID:", scan.state.ID)
+	}
+
+	// open the project database, if we're using one
+	var db *store.Store
+	var runId int64
+	if *projectPtr != "" {
+		db, err = store.Open(*projectPtr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer db.Close()
+		runId, err = db.StartRun(*searchPtr, *extensionsPtr)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// stream findings as we go?
+	var jsonl *jsonlWriter
+	if *jsonlPtr != "" {
+		jsonl, err = newJsonlWriter(*jsonlPtr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer jsonl.Close()
+	}
+
+	// Ctrl-C stops dorking and fetching, but we still output whatever
+	// we've got. a second Ctrl-C kills us outright.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	// split file extensions into slice and dork each one
 	returnedUrls := make(chan dorkResult)
 	tracker := make(chan empty)
 
-	// a nice, ugly way of calculating the tracker count
-	// so we can wait for threads and not get race conditions.
-	multiplier := 0
-	if settings.BingKey != "" {
-		multiplier++
+	// search results are cached, as they cost money
+	cache := &searchCache{
+		dir:     filepath.Join(dragonVomitDir, "cache", "search"),
+		ttl:     *cacheTtlPtr,
+		refresh: refresh,
 	}
-	if settings.GoogleKey != "" && settings.GoogleId != "" {
-		multiplier++
+
+	// fetched documents are only cached if asked, as it adds up. offline
+	// there's nowhere else to get them from.
+	var docs *docCache
+	if *docCachePtr || offline {
+		docs, err = openDocCache(filepath.Join(dragonVomitDir, "cache", "documents"))
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	// TODO: implement a sync.Map for recording results. We can use the
-	// keys to add unqiue items, and record their source as the value.
-	dorkTrackerCount := len(strings.Split(*extensionsPtr, ",")) * multiplier
+	// keep copies of the documents as evidence?
+	var archive *documentArchive
+	if *savePtr != "" {
+		archive, err = newDocumentArchive(*savePtr)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// search APIs and documents share the same proxy, user agent, etc.
+	// only the documents get the extra headers and cookies, as they're
+	// meant for the target, not Bing or Google.
+	clientConfig := httpclient.Config{
+		Proxy:          *proxyPtr,
+		UserAgents:     []string{*userAgentPtr},
+		Insecure:       *insecurePtr,
+		ConnectTimeout: *connectTimeoutPtr,
+		ReadTimeout:    *readTimeoutPtr,
+	}
+	if *userAgentsPtr != "" {
+		clientConfig.UserAgents, err = httpclient.ReadUserAgents(*userAgentsPtr)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	searchClient, err := httpclient.New(clientConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	clientConfig.Headers = make(http.Header)
+	for _, header := range headers {
+		name, value, err := httpclient.ParseHeader(header)
+		if err != nil {
+			log.Fatal(err)
+		}
+		clientConfig.Headers.Add(name, value)
+	}
+	if *cookiePtr != "" {
+		clientConfig.Headers.Set("Cookie", *cookiePtr)
+	}
+	docClient, err = httpclient.New(clientConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// go as slowly as we've been asked to
+	throttle := newFetchThrottle(*ratePtr, *hostRatePtr, *jitterPtr, *hostThreadsPtr)
+
+	// only run the dorks that have been configured, and none at all
+	// if we're offline
+	// disable Bing during testing to avoid bankruptcy
+	type dork struct{ engine, extension string }
+	var dorks []dork
 	for _, currentExtension := range strings.Split(*extensionsPtr, ",") {
-		// only run the dorks that have been configured
-		// disable Bing during testing to avoid bankruptcy
+		if offline {
+			break
+		}
 		if settings.BingKey != "" {
-			go bingDork(settings.BingKey, *searchPtr, currentExtension, returnedUrls, tracker)
+			dorks = append(dorks, dork{"bing", currentExtension})
 		}
 		if settings.GoogleKey != "" && settings.GoogleId != "" {
-			go googleDork(settings.GoogleKey, settings.GoogleId, *searchPtr, currentExtension, returnedUrls, tracker)
+			dorks = append(dorks, dork{"google", currentExtension})
 		}
 	}
 
-	// get a de-duplicated list of URLs to investigate
-	var dedupedReturnedUrls []string
+	// TODO: implement a sync.Map for recording results. We can use the
+	// keys to add unqiue items, and record their source as the value.
+	dorkTrackerCount := 0
+	var dorkFailuresMu sync.Mutex
+	var dorkFailures []failure
+	for _, d := range dorks {
+		// a resumed scan already has these results
+		if scan.dorkDone(d.engine, d.extension) {
+			continue
+		}
+		dorkTrackerCount++
+		go func(d dork) {
+			var err error
+			switch d.engine {
+			case "bing":
+				err = bingDork(ctx, searchClient, cache, settings.BingKey, *searchPtr, d.extension, returnedUrls)
+			case "google":
+				err = googleDork(ctx, searchClient, cache, settings.GoogleKey, settings.GoogleId, *searchPtr, d.extension, returnedUrls)
+			}
+			if err == nil || errors.Is(err, errNoMatches) {
+				scan.finishDork(d.engine, d.extension)
+			} else if ctx.Err() == nil {
+				if !silent {
+					fmt.Println("[!]", err)
+				}
+				dorkFailuresMu.Lock()
+				dorkFailures = append(dorkFailures, failure{
+					item:   fmt.Sprintf("%s dork for %s", d.engine, d.extension),
+					reason: err.Error(),
+				})
+				dorkFailuresMu.Unlock()
+			}
+			var e empty
+			tracker <- e
+		}(d)
+	}
+
+	// get a de-duplicated list of URLs to investigate, starting with
+	// any from the scan we're resuming. offline, the document cache is
+	// all we've got.
+	dedupedReturnedUrls := scan.urls()
+	if offline {
+		for _, r := range docs.urls(*searchPtr, strings.Split(*extensionsPtr, ",")) {
+			if !slices.ContainsFunc(dedupedReturnedUrls, func(d dorkResult) bool { return d.url == r.url }) {
+				dedupedReturnedUrls = append(dedupedReturnedUrls, r)
+			}
+		}
+	}
 	go func() {
 		for r := range returnedUrls {
-			if !slices.Contains(dedupedReturnedUrls, r.url) {
-				dedupedReturnedUrls = append(dedupedReturnedUrls, r.url)
+			// record every engine that found it, not just the first
+			if db != nil {
+				if err := db.AddSource(runId, r.url, docFileName(r.url), r.searchEngine, r.crawled); err != nil {
+					fmt.Fprintln(os.Stderr, "[!]", err)
+				}
+			}
+			i := slices.IndexFunc(dedupedReturnedUrls, func(d dorkResult) bool { return d.url == r.url })
+			if i == -1 {
+				dedupedReturnedUrls = append(dedupedReturnedUrls, r)
+				scan.addUrl(r)
 				if !silent {
 					fmt.Printf("[%s] %s\n", r.searchEngine, r.url)
 				}
+			} else if dedupedReturnedUrls[i].crawled.IsZero() {
+				// only Bing gives us a crawl date, so keep it if we can
+				dedupedReturnedUrls[i].crawled = r.crawled
 			}
 		}
 		var e empty
@@ -194,26 +475,96 @@ func main() {
 	}
 
 	var results []analysisResult
+	// stream out results we didn't have to fetch, too
+	emit := func(result analysisResult) {
+		if jsonl != nil {
+			if err := jsonl.writeResult(result); err != nil {
+				fmt.Fprintln(os.Stderr, "[!]", err)
+			}
+		}
+	}
+
+	// skip anything the scan we're resuming or an earlier run already
+	// settled, but keep what it found so this run's output is complete.
+	// offline, the point is to analyse it all again.
+	hashes := newDocHashes()
+	resumed := make(map[string]analysisResult)
+	if !offline {
+		for _, result := range scan.completed() {
+			resumed[result.url] = result
+		}
+	}
+	var toFetch []dorkResult
+	var resumedCount, projectCount int
+	for _, r := range dedupedReturnedUrls {
+		if result, ok := resumed[r.url]; ok {
+			results = append(results, result)
+			hashes.seed(result)
+			emit(result)
+			resumedCount++
+			continue
+		}
+		if db != nil && !offline {
+			status, httpStatus, err := db.Status(r.url)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "[!]", err)
+			}
+			if settled(status, httpStatus) {
+				result, err := loadResult(db, r)
+				if err == nil {
+					results = append(results, result)
+					scan.addResult(result)
+					hashes.seed(result)
+					emit(result)
+					projectCount++
+					continue
+				}
+				fmt.Fprintln(os.Stderr, "[!]", err)
+			}
+		}
+		toFetch = append(toFetch, r)
+	}
+	if !silent && resumedCount > 0 {
+		fmt.Printf("[i] Skipping %d document(s) already done in scan %s\n", resumedCount, scan.state.ID)
+	}
+	if !silent && projectCount > 0 {
+		fmt.Printf("[i] Skipping %d document(s) already done in %s\n", projectCount, *projectPtr)
+	}
+	dedupedReturnedUrls = toFetch
+
 	gather := make(chan analysisResult)
-	docUrls := make(chan string, *threadCount)
+	docUrls := make(chan dorkResult, *threadCount)
 
 	// start our file processing worker threads
 	for i := 0; i < *threadCount; i++ {
-		go worker(tracker, gather, docUrls)
+		go worker(ctx, docs, throttle, archive, hashes, tracker, gather, docUrls)
 	}
 
 	// thread to gather results
 	go func() {
 		for r := range gather {
 			results = append(results, r)
+			scan.addResult(r)
+			emit(r)
+			if db != nil {
+				if err := saveResult(db, runId, r); err != nil {
+					fmt.Fprintln(os.Stderr, "[!]", err)
+				}
+			}
 		}
 		var e empty
 		tracker <- e
 	}()
 
-	// add document URLs to channel for workers to process
+	// add document URLs to channel for workers to process, until
+	// we're interrupted
+feed:
 	for _, r := range dedupedReturnedUrls {
-		docUrls <- r
+		select {
+		case docUrls <- r:
+		case <-ctx.Done():
+			break feed
+		}
 	}
 
 	// clean up the threads
@@ -224,25 +575,120 @@ func main() {
 	close(gather)
 	<-tracker
 
+	if !silent && !offline {
+		fmt.Printf("[i] Search queries: %d from cache, %d paid\n", cache.hits.Load(), cache.paid.Load())
+	}
+
+	// own up to what we couldn't get
+	if !silent {
+		printFailures(dorkFailures, results)
+	}
+
+	// checkpoint whatever's left if we were interrupted, so we can
+	// resume. otherwise there's nothing to come back to.
+	if ctx.Err() != nil {
+		scan.save()
+		if !silent {
+			fmt.Println("[!] Interrupted - outputting partial results")
+			fmt.Printf("[i] Resume with: -resume %s\n", scan.state.ID)
+		}
+	} else {
+		scan.remove()
+	}
+
+	if db != nil {
+		if err := db.FinishRun(runId); err != nil {
+			fmt.Fprintln(os.Stderr, "[!]", err)
+		}
+	}
+
 	// output pulls unique loot for display.
 	if !silent {
-		fmt.Println("[i] Printing results:\n")
+		fmt.Print("[i] Printing results:\n\n")
 	}
 	finalResults := processResultsToFinal(results)
+	sortTimeline(finalResults.Timeline)
+	workPatterns := inferWorkPatterns(finalResults.Timeline)
+	finalResults.WorkPatterns = &workPatterns
+	finalResults.Domains = inferDomains(finalResults)
+	userFormat := inferUserFormat(finalResults)
+	finalResults.UserFormat = &userFormat
+	finalResults.People = buildPeople(finalResults)
+	finalResults.SoftwareInventory = buildSoftwareInventory(finalResults)
+	finalResults.Platforms, finalResults.OSDistribution = inferPlatforms(finalResults)
 	// export to JSON?
 	if *jsonExportPtr != "" {
 		jsonContent, err := json.Marshal(finalResults)
 		if err != nil {
-			fmt.Println("[!] Unable to marshal results to JSON")
+			fmt.Fprintln(os.Stderr, "[!] Unable to marshal results to JSON")
 		} else {
 			err = os.WriteFile(*jsonExportPtr, jsonContent, 0644)
 			if err != nil {
-				fmt.Println("[!] Error writing JSON to file")
+				fmt.Fprintln(os.Stderr, "[!] Error writing JSON to file")
 			}
 		}
 	}
 
-	_ = printResults(finalResults)
+	// write the timeline?
+	if *timelinePtr != "" {
+		if err := writeTimeline(finalResults.Timeline, *timelinePtr); err != nil {
+			fmt.Fprintln(os.Stderr, "[!]", err)
+		}
+	}
+
+	// export the entity graph?
+	if *graphmlPtr != "" || *neo4jPtr != "" {
+		graph := buildGraph(finalResults)
+		if *graphmlPtr != "" {
+			if err := writeGraphML(graph, *graphmlPtr); err != nil {
+				fmt.Fprintln(os.Stderr, "[!]", err)
+			}
+		}
+		if *neo4jPtr != "" {
+			if err := writeNeo4jCsv(graph, *neo4jPtr); err != nil {
+				fmt.Fprintln(os.Stderr, "[!]", err)
+			}
+		}
+	}
+
+	// export as CSV?
+	if *csvPtr != "" {
+		if err := writeCsvExport(finalResults, *csvPtr); err != nil {
+			fmt.Fprintln(os.Stderr, "[!]", err)
+		}
+	}
+
+	// write an HTML report?
+	if *htmlPtr != "" {
+		if err := writeHtmlReport(finalResults, *searchPtr, *redactPtr, *htmlPtr); err != nil {
+			fmt.Fprintln(os.Stderr, "[!]", err)
+		}
+	}
+
+	// write a Markdown report?
+	if *markdownPtr != "" {
+		if err := writeMarkdownReport(finalResults, *searchPtr, *redactPtr, *markdownPtr); err != nil {
+			fmt.Fprintln(os.Stderr, "[!]", err)
+		}
+	}
+
+	// export for Maltego?
+	if *maltegoPtr != "" {
+		if err := writeMaltegoTable(finalResults, *searchPtr, *maltegoPtr); err != nil {
+			fmt.Fprintln(os.Stderr, "[!]", err)
+		}
+	}
+
+	// write username and email lists?
+	if *userlistPtr != "" {
+		if err := writeUserLists(userFormat, *userlistPtr); err != nil {
+			fmt.Fprintln(os.Stderr, "[!]", err)
+		}
+	}
+
+	if *jsonlPtr != "-" {
+		_ = printResults(finalResults, *redactPtr)
+	}
 
 }
 
@@ -251,10 +697,23 @@ func main() {
 func processResultsToFinal(results []analysisResult) (r FinalResult) {
 	// a long, ugly process but makes it easier to output clean tables
 	for _, result := range results {
+		// nothing to show for documents we couldn't analyse
+		if result.status != statusAnalysed {
+			continue
+		}
+
 		// get the file name
-		urlParts := strings.Split(result.url, "/")
-		dorkedFileName := urlParts[len(urlParts)-1]
-		dorkedFileName, _ = url.QueryUnescape(dorkedFileName)
+		dorkedFileName := docFileName(result.url)
+
+		// keep a list of every document we analysed
+		r.Documents = append(r.Documents, Document{
+			FileName:     dorkedFileName,
+			FileUrl:      result.url,
+			FileType:     result.fileType,
+			SearchEngine: result.searchEngine,
+			SHA256:       result.sha256,
+			AppVersion:   result.appVersion,
+		})
 
 		// process External links
 		if len(result.ExternalLinks) > 0 {
@@ -400,6 +859,47 @@ func processResultsToFinal(results []analysisResult) (r FinalResult) {
 			}
 		}
 
+		// process fonts
+		if len(result.Fonts) > 0 {
+			for _, font := range result.Fonts {
+				addition := Font{
+					Font:     font,
+					FileName: dorkedFileName,
+					FileUrl:  result.url,
+				}
+				r.Fonts = append(r.Fonts, addition)
+			}
+		}
+
+		// process secrets
+		if len(result.Secrets) > 0 {
+			for _, secret := range result.Secrets {
+				addition := Secret{
+					Type:     secret.Type,
+					Value:    secret.Value,
+					FileName: dorkedFileName,
+					FileUrl:  result.url,
+				}
+				r.Secrets = append(r.Secrets, addition)
+			}
+		}
+
+		// process network intel
+		if len(result.NetworkIntel) > 0 {
+			for _, intel := range result.NetworkIntel {
+				addition := NetworkIntel{
+					Type:     intel.Type,
+					Value:    intel.Value,
+					FileName: dorkedFileName,
+					FileUrl:  result.url,
+				}
+				r.NetworkIntel = append(r.NetworkIntel, addition)
+			}
+		}
+
+		// process timeline events
+		r.Timeline = append(r.Timeline, timelineEvents(result, dorkedFileName)...)
+
 		// process embedded docs flags
 		if result.EmbeddedDocs {
 			addition := EmbeddedDoc{
@@ -419,11 +919,23 @@ func processResultsToFinal(results []analysisResult) (r FinalResult) {
 		}
 	}
 
+	// identical copies were only analysed once, so just note where
+	// else they turned up
+	for _, result := range results {
+		if result.status != statusDuplicate {
+			continue
+		}
+		i := slices.IndexFunc(r.Documents, func(d Document) bool { return d.FileUrl == result.duplicateOf })
+		if i != -1 {
+			r.Documents[i].AlsoAt = append(r.Documents[i].AlsoAt, result.url)
+		}
+	}
+
 	return
 }
 
 // printResults prints final result struct to stdout
-func printResults(results FinalResult) error {
+func printResults(results FinalResult, redactMode string) error {
 	w := tabwriter.NewWriter(os.Stdout, 0, 6, 8, ' ', 0)
 
 	// print files with embedded docs
@@ -525,6 +1037,61 @@ func printResults(results FinalResult) error {
 		fmt.Println()
 	}
 
+	// print the normalised software inventory, flagging anything past
+	// end-of-life as a likely payload target
+	if len(results.SoftwareInventory) > 0 {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", "Vendor", "Product", "Version", "OS", "Documents", "End-of-Life")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", "======", "=======", "=======", "==", "=========", "===========")
+		for _, item := range results.SoftwareInventory {
+			eol := item.EOLDate
+			if item.EOL {
+				eol = fmt.Sprintf("YES (%s)", item.EOLDate)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", item.Vendor, item.Product, item.Version, item.OS, item.Count, eol)
+		}
+		w.Flush()
+		fmt.Println()
+	}
+
+	// print how the documents split across operating systems
+	if len(results.OSDistribution) > 0 {
+		fmt.Fprintf(w, "%s\t%s\n", "Probable OS", "Documents")
+		fmt.Fprintf(w, "%s\t%s\n", "===========", "=========")
+		for _, osCount := range results.OSDistribution {
+			fmt.Fprintf(w, "%s\t%d\n", osCount.OS, osCount.Count)
+		}
+		w.Flush()
+		fmt.Println()
+	}
+
+	// print timezones and working hours
+	if results.WorkPatterns != nil && len(results.WorkPatterns.Offsets) > 0 {
+		fmt.Fprintf(w, "%s\t%s\n", "UTC Offset", "Timestamps")
+		fmt.Fprintf(w, "%s\t%s\n", "==========", "==========")
+		for _, offset := range results.WorkPatterns.Offsets {
+			fmt.Fprintf(w, "%s\t%d\n", offset.Offset, offset.Count)
+		}
+		w.Flush()
+		fmt.Println()
+	}
+	if results.WorkPatterns != nil && len(results.WorkPatterns.Authors) > 0 {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", "Author", "UTC Offset(s)", "Typical Hours", "0     6     12    18    |", "Samples")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", "======", "=============", "=============", "=========================", "=======")
+		for _, author := range results.WorkPatterns.Authors {
+			// with no offsets anywhere there's nothing to assume from,
+			// so the hours are just UTC
+			offsets := strings.Join(author.Offsets, ", ")
+			if offsets == "" && len(results.WorkPatterns.Offsets) == 0 {
+				offsets = "UTC"
+			} else if offsets == "" {
+				offsets = "(assumed)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%02d:00-%02d:59\t%s|\t%d\n", author.Person, offsets, author.Start, author.End, renderHistogram(author.Histogram), author.Samples)
+		}
+		w.Flush()
+		fmt.Println()
+	}
+
 	// print hostnames
 	if len(results.Hostnames) > 0 {
 		fmt.Fprintf(w, "%s\t%s\t%s\n", "Hostname", "Dorked File Name", "Dorked URL")
@@ -536,6 +1103,42 @@ func printResults(results FinalResult) error {
 		fmt.Println()
 	}
 
+	// print internal network intel - kept apart from external links
+	if len(results.NetworkIntel) > 0 {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", "Network Intel", "Value", "Dorked File Name", "Dorked URL")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", "=============", "=====", "================", "==========")
+		for _, intel := range results.NetworkIntel {
+			fmt.Fprintf(w, "%s\t%s\t\"%s\"\t%.45s...\n", intel.Type, intel.Value, intel.FileName, intel.FileUrl)
+		}
+		w.Flush()
+		fmt.Println()
+	}
+
+	// print inferred AD domains
+	if len(results.Domains) > 0 {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", "AD Domain (NetBIOS)", "DNS Name(s)", "Confidence", "Evidence")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", "===================", "===========", "==========", "========")
+		for _, domain := range results.Domains {
+			fmt.Fprintf(w, "%s\t%s\t%s (%.1f)\t%d item(s), eg. %s\n", domain.NetBIOS, strings.Join(domain.DNSNames, ", "), domain.Confidence, domain.Score, len(domain.Evidence), domain.Evidence[0])
+		}
+		w.Flush()
+		fmt.Println()
+	}
+
+	// print the naming convention, if we worked one out
+	if results.UserFormat != nil && (results.UserFormat.UsernameFormat != "" || results.UserFormat.EmailFormat != "") {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", "Naming Convention", "Format", "Matches")
+		fmt.Fprintf(w, "%s\t%s\t%s\n", "=================", "======", "=======")
+		if results.UserFormat.UsernameFormat != "" {
+			fmt.Fprintf(w, "%s\t%s\t%d\n", "Username", results.UserFormat.UsernameFormat, results.UserFormat.UsernameMatches)
+		}
+		if results.UserFormat.EmailFormat != "" {
+			fmt.Fprintf(w, "%s\t%s@%s\t%d\n", "Email", results.UserFormat.EmailFormat, results.UserFormat.EmailDomain, results.UserFormat.EmailMatches)
+		}
+		w.Flush()
+		fmt.Println()
+	}
+
 	// print grep results
 	if len(results.GreppedValues) > 0 {
 		fmt.Fprintf(w, "%s\t%s\n", "Grep Result", "Dorked URL")
@@ -547,6 +1150,20 @@ func printResults(results FinalResult) error {
 		fmt.Println()
 	}
 
+	// print secrets - redacted depending on redactMode
+	if len(results.Secrets) > 0 {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", "Secret Type", "Secret", "Dorked File Name", "Dorked URL")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", "===========", "======", "================", "==========")
+		for _, secret := range results.Secrets {
+			finding := secrets.Finding{Type: secret.Type, Value: secret.Value}
+			// keep multi-line values from wrecking the table
+			value := strings.Join(strings.Fields(finding.Redact(redactMode)), " ")
+			fmt.Fprintf(w, "%s\t%s\t\"%s\"\t%.45s...\n", secret.Type, value, secret.FileName, secret.FileUrl)
+		}
+		w.Flush()
+		fmt.Println()
+	}
+
 	// print names of users
 	if len(results.Names) > 0 {
 		fmt.Fprintf(w, "%s\t%s\t%s\n", "Name", "Dorked File Name", "Dorked URL")
@@ -568,6 +1185,21 @@ func printResults(results FinalResult) error {
 		fmt.Println()
 	}
 
+	// one row per person, with everything we could tie to them
+	if len(results.People) > 0 {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", "Person", "Username(s)", "Email(s)", "Documents")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", "======", "===========", "========", "=========")
+		for _, person := range results.People {
+			name := person.Name
+			if name == "" {
+				name = "(unknown)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", name, strings.Join(person.Usernames, ", "), strings.Join(person.Emails, ", "), len(person.Documents))
+		}
+		w.Flush()
+		fmt.Println()
+	}
+
 	if len(results.Usernames) > 0 {
 		fmt.Fprintf(w, "%s\t%s\t%s\n", "Username", "Dorked File Name", "Dorked URL")
 		fmt.Fprintf(w, "%s\t%s\t%s\n", "========", "================", "==========")
@@ -583,13 +1215,18 @@ func printResults(results FinalResult) error {
 
 // worker processes each file it pulls from the docUrls channel, extracting
 // metadata and returning results to the gather channel.
-func worker(tracker chan empty, gather chan analysisResult, docUrls chan string) {
+func worker(ctx context.Context, cache *docCache, throttle *fetchThrottle, archive *documentArchive, hashes *docHashes, tracker chan empty, gather chan analysisResult, docUrls chan dorkResult) {
 	// TODO: process each URL. Identify file type, and send to necessary
 	//       function to extract metadata.
 	re := regexp.MustCompile(`(\.[a-zA-Z]*)$`)
 
-	for url := range docUrls {
-		matches := re.FindStringSubmatch(url)
+	for doc := range docUrls {
+		// drain anything left once we've been interrupted
+		if ctx.Err() != nil {
+			continue
+		}
+
+		matches := re.FindStringSubmatch(doc.url)
 		var extension string
 		if len(matches) > 0 {
 			extension = matches[0]
@@ -597,85 +1234,282 @@ func worker(tracker chan empty, gather chan analysisResult, docUrls chan string)
 			extension = ""
 		}
 		result := analysisResult{
-			url:      url,
-			fileType: strings.ToLower(extension),
+			url:          doc.url,
+			fileType:     strings.ToLower(extension),
+			searchEngine: doc.searchEngine,
+			crawled:      doc.crawled,
 		}
 
 		if !silent {
 			fmt.Println("[i] Processing file:", result.url)
 		}
 
-		resp, err := http.Get(result.url)
-		if err != nil {
-			if !silent {
+		buf, ok := fetchDocument(ctx, cache, throttle, &result)
+		if !ok {
+			gather <- result
+			continue
+		}
+
+		// every URL gets archived, even the duplicates, so the
+		// manifest shows everywhere a document was found
+		if err := archive.save(result, buf); err != nil && !silent {
+			fmt.Println("[!]", err)
+		}
+
+		// the same document at another URL only gets analysed once,
+		// and this URL gets pointed at the first
+		entry, first := hashes.claim(result.sha256)
+		if !first {
+			original := entry.wait()
+			result.status = original.status
+			result.err = original.err
+			if original.status == statusAnalysed {
+				result.status = statusDuplicate
+				result.duplicateOf = original.url
+			}
+			gather <- result
+			continue
+		}
+
+		analyseDocument(buf, &result)
+		entry.finish(result)
+		gather <- result
+	}
+
+	var e empty
+	tracker <- e
+}
+
+// fetchDocument gets a document's content, from the cache if we've got
+// it. Failures are recorded in result.
+func fetchDocument(ctx context.Context, cache *docCache, throttle *fetchThrottle, result *analysisResult) ([]byte, bool) {
+	if !refresh {
+		if entry, buf, ok := cache.get(result.url); ok {
+			result.fetched = entry.Fetched
+			result.httpStatus = entry.HTTPStatus
+			result.contentType = entry.ContentType
+			result.size = entry.Size
+			result.sha256 = entry.SHA256
+			// the limits may have changed since it was cached
+			return buf, acceptDocument(buf, result)
+		}
+	}
+	if offline {
+		if !silent {
+			fmt.Println("[!] Not in the document cache:", result.url)
+		}
+		result.status = statusFetchFailed
+		result.err = "not in the document cache"
+		return nil, false
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, result.url, nil)
+	if err != nil {
+		if !silent {
+			fmt.Println("[!] Invalid URL:", result.url)
+		}
+		result.status = statusFetchFailed
+		result.err = err.Error()
+		return nil, false
+	}
+	// try again if it looks like a blip, and only complain once we've
+	// given up on it
+	var buf []byte
+	err = retries.do(ctx, result.url, func() error {
+		var err error
+		buf, err = downloadDocument(ctx, throttle, req, result)
+		return err
+	})
+	if err != nil {
+		if !silent && ctx.Err() == nil {
+			switch result.status {
+			case statusFetchFailed:
 				fmt.Println("[!] Failed to fetch:", result.url)
-				continue
+			case statusHttpError:
+				fmt.Printf("[!] HTTP %d fetching: %s\n", result.httpStatus, result.url)
+			case statusReadFailed:
+				fmt.Println("[!] Failed to read content of:", result.url)
 			}
 		}
+		return nil, false
+	}
+
+	result.size = int64(len(buf))
+	result.sha256 = fmt.Sprintf("%x", sha256.Sum256(buf))
+	if !acceptDocument(buf, result) {
+		return nil, false
+	}
+
+	err = cache.put(docIndexEntry{
+		Url:         result.url,
+		SHA256:      result.sha256,
+		Size:        result.size,
+		ContentType: result.contentType,
+		HTTPStatus:  result.httpStatus,
+		Fetched:     result.fetched,
+	}, buf)
+	if err != nil && !silent {
+		fmt.Println("[!]", err)
+	}
+
+	return buf, true
+}
+
+// downloadDocument makes a single attempt at fetching a document. How
+// it went is recorded in result, and the error says whether it's worth
+// another go.
+func downloadDocument(ctx context.Context, throttle *fetchThrottle, req *http.Request, result *analysisResult) ([]byte, error) {
+	// forget about any earlier attempt
+	result.status = ""
+	result.err = ""
+
+	release, err := throttle.wait(ctx, result.url)
+	if err != nil {
+		result.status = statusFetchFailed
+		result.err = err.Error()
+		return nil, err
+	}
+	// the host's slot is held until we've read the whole body
+	defer release()
 
-		buf, err := io.ReadAll(resp.Body)
+	resp, err := docClient.Do(req)
+	if err != nil {
+		result.status = statusFetchFailed
+		result.err = err.Error()
+		return nil, err
+	}
+	// we're done with the body before the result goes anywhere
+	// near the gather channel, so deferring is fine here
+	defer resp.Body.Close()
+
+	result.fetched = time.Now()
+	result.httpStatus = resp.StatusCode
+	result.contentType = resp.Header.Get("Content-Type")
+
+	if resp.StatusCode >= 400 {
+		result.status = statusHttpError
+		result.err = resp.Status
+		return nil, &statusError{
+			code:       resp.StatusCode,
+			status:     resp.Status,
+			retryAfter: resp.Header.Get("Retry-After"),
+		}
+	}
+
+	// don't bother downloading something we already know is too big
+	if maxSize > 0 && resp.ContentLength > maxSize {
+		result.size = resp.ContentLength
+		reject(result, fmt.Sprintf("%d bytes is over the size limit", resp.ContentLength))
+		return nil, errors.New(result.err)
+	}
+
+	// read one byte past the limit, so we can tell if it was hit
+	var body io.Reader = resp.Body
+	if maxSize > 0 {
+		body = io.LimitReader(resp.Body, maxSize+1)
+	}
+	buf, err := io.ReadAll(body)
+	if err != nil {
+		result.status = statusReadFailed
+		result.err = err.Error()
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// acceptDocument checks a document is within the size limit, rejecting
+// it if not
+func acceptDocument(buf []byte, result *analysisResult) bool {
+	if maxSize > 0 && int64(len(buf)) > maxSize {
+		reject(result, "over the size limit")
+		return false
+	}
+	return true
+}
+
+// reject records why a document wasn't analysed
+func reject(result *analysisResult, reason string) {
+	if !silent {
+		fmt.Printf("[!] Rejected %s: %s\n", result.url, reason)
+	}
+	result.status = statusRejected
+	result.err = reason
+}
+
+// analyseDocument pulls the metadata out of a document, based on its
+// file type
+func analyseDocument(buf []byte, result *analysisResult) {
+	switch result.fileType {
+	case ".pdf":
+		// Process buf as a PDF
+		metadata, err := pdfParse(buf)
 		if err != nil {
 			if !silent {
-				fmt.Println("[!] Failed to read content of:", result.url)
-				continue
+				fmt.Println("[!] Error processing PDF file:", result.url)
 			}
+			result.status = statusParseFailed
+			result.err = err.Error()
+			return
+		}
+		if metadata.Author != "" {
+			result.Names = append(result.Names, metadata.Author)
 		}
+		// PDFs only have the one author, so they did everything
+		result.creator = metadata.Author
+		result.lastModifiedBy = metadata.Author
+		result.created = metadata.Created
+		result.modified = metadata.Modified
+		if len(metadata.Software) > 0 {
+			result.Software = append(result.Software, metadata.Software...)
+		}
+		result.Secrets = append(result.Secrets, metadata.Secrets...)
+		result.NetworkIntel = append(result.NetworkIntel, metadata.Intel...)
 
-		switch result.fileType {
-		case ".pdf":
-			// Process buf as a PDF
-			author, software, err := pdfParse(buf)
-			if err != nil {
-				if !silent {
-					fmt.Println("[!] Error processing PDF file:", result.url)
-				}
-				resp.Body.Close()
-				continue
-			}
-			if author != "" {
-				result.Names = append(result.Names, author)
-			}
-			if len(software) > 0 {
-				result.Software = append(result.Software, software...)
+	case ".docx", ".xlsx", ".pptx":
+		// process as an office document
+		metadata, err := officeParse(buf)
+		if err != nil {
+			if !silent {
+				fmt.Println("[!] Error processing Office file:", result.url)
 			}
+			result.status = statusParseFailed
+			result.err = err.Error()
+			return
+		}
 
-		case ".docx", ".xlsx", ".pptx":
-			// process as an office document
-			metadata, err := officeParse(buf)
-			if err != nil {
-				if !silent {
-					fmt.Println("[!] Error processing Office file:", result.url)
-				}
-				resp.Body.Close()
-				continue
-			}
-
-			// process metadata out into analysisResult
-			// probably a cleaner way to do this, but meh.
-			result.ExternalLinks = append(result.ExternalLinks, metadata.ExternalLinks...)
-			result.ImageLinks = append(result.ImageLinks, metadata.ImageLinks...)
-			result.FilePaths = append(result.FilePaths, metadata.FilePaths...)
-			result.Printers = append(result.Printers, metadata.Printers...)
-			result.Hostnames = append(result.Hostnames, metadata.Hostnames...)
-			result.Emails = append(result.Emails, metadata.Emails...)
-			result.Names = append(result.Names, metadata.Names...)
-			result.Usernames = append(result.Usernames, metadata.Usernames...)
-			result.GrepKeywords = append(result.GrepKeywords, metadata.GrepKeywords...)
-			result.HiddenSheets = append(result.HiddenSheets, metadata.HiddenSheets...)
-			result.LastSavedPath = append(result.LastSavedPath, metadata.LastSavedPath...)
+		// process metadata out into analysisResult
+		// probably a cleaner way to do this, but meh.
+		result.ExternalLinks = append(result.ExternalLinks, metadata.ExternalLinks...)
+		result.ImageLinks = append(result.ImageLinks, metadata.ImageLinks...)
+		result.FilePaths = append(result.FilePaths, metadata.FilePaths...)
+		result.Printers = append(result.Printers, metadata.Printers...)
+		result.Hostnames = append(result.Hostnames, metadata.Hostnames...)
+		result.Emails = append(result.Emails, metadata.Emails...)
+		result.Names = append(result.Names, metadata.Names...)
+		result.Usernames = append(result.Usernames, metadata.Usernames...)
+		result.GrepKeywords = append(result.GrepKeywords, metadata.GrepKeywords...)
+		result.HiddenSheets = append(result.HiddenSheets, metadata.HiddenSheets...)
+		result.LastSavedPath = append(result.LastSavedPath, metadata.LastSavedPath...)
+		result.Fonts = append(result.Fonts, metadata.Fonts...)
+		result.creator = metadata.CoreProperties.Creator
+		result.lastModifiedBy = metadata.CoreProperties.LastModifiedBy
+		result.created, _ = parseW3CDTF(metadata.CoreProperties.Created)
+		result.modified, _ = parseW3CDTF(metadata.CoreProperties.Modified)
+		result.printed, _ = parseW3CDTF(metadata.CoreProperties.LastPrinted)
+		result.appVersion = metadata.AppProperties.Version
+		result.Secrets = append(result.Secrets, metadata.Secrets...)
+		result.NetworkIntel = append(result.NetworkIntel, metadata.NetworkIntel...)
+		// name the actual app if we can, it helps the inventory
+		if metadata.AppProperties.Application != "" {
+			result.Software = append(result.Software, fmt.Sprintf("%s %s", metadata.AppProperties.Application, metadata.AppProperties.GetMajorVersion()))
+		} else {
 			result.Software = append(result.Software, fmt.Sprintf("Office %s", metadata.AppProperties.GetMajorVersion()))
-			result.EmbeddedDocs = metadata.EmbeddedDocs
-			result.EmbeddedMedia = metadata.EmbeddedMedia
-
 		}
+		result.EmbeddedDocs = metadata.EmbeddedDocs
+		result.EmbeddedMedia = metadata.EmbeddedMedia
 
-		// explicitly close - if we defer it stays open while
-		// the channel is opening. I want to keep as small of
-		// a memory footprint as possible.
-		resp.Body.Close()
-		gather <- result
 	}
 
-	var e empty
-	tracker <- e
+	result.status = statusAnalysed
 }
@@ -0,0 +1,81 @@
+package main
+
+import "strings"
+
+// maltegoColumns are the entity types for each column of the Maltego
+// table. Use Import > Import Graph from Table, keep the header row, and
+// map each column to the entity type in brackets. Entities on the same
+// row get linked, left to right.
+var maltegoColumns = []string{
+	"Domain (maltego.Domain)",
+	"Document (maltego.Document)",
+	"Person (maltego.Person)",
+	"Email Address (maltego.EmailAddress)",
+	"DNS Name (maltego.DNSName)",
+	"URL (maltego.URL)",
+}
+
+// buildMaltegoTable lays the results out as a Maltego entity table.
+// Every row hangs off the dorked domain, then the document it was
+// found in.
+func buildMaltegoTable(results FinalResult, domain string) [][]string {
+	rows := [][]string{maltegoColumns}
+	seen := make(map[string]bool)
+	addRow := func(fileName, person, email, host, url string) {
+		row := []string{domain, fileName, person, email, host, url}
+		// the cells are free text, so join on something they won't contain
+		key := strings.Join(row, "\x00")
+		if !seen[key] {
+			seen[key] = true
+			rows = append(rows, row)
+		}
+	}
+
+	// documents themselves, with where they came from
+	for _, d := range results.Documents {
+		addRow(d.FileName, "", "", "", d.FileUrl)
+	}
+
+	// people and their emails
+	for _, n := range results.Names {
+		name := normaliseName(n.Name)
+		if name == "" {
+			continue
+		}
+		addRow(n.FileName, name, "", "", "")
+	}
+	for _, person := range results.People {
+		if person.Name == "" {
+			continue
+		}
+		for _, email := range person.Emails {
+			addRow("", person.Name, email, "", "")
+		}
+	}
+	for _, e := range results.Emails {
+		addRow(e.FileName, "", strings.ToLower(e.EmailAddr), "", "")
+	}
+
+	// hosts and links
+	for _, h := range results.Hostnames {
+		addRow(h.FileName, "", "", h.Hostname, "")
+	}
+	for _, intel := range results.NetworkIntel {
+		switch intel.Type {
+		case "internal-fqdn", "ad-domain":
+			addRow(intel.FileName, "", "", intel.Value, "")
+		case "intranet-url", "internal-uri":
+			addRow(intel.FileName, "", "", "", intel.Value)
+		}
+	}
+	for _, l := range results.ExternalLinks {
+		addRow(l.FileName, "", "", "", l.ExternalLink)
+	}
+
+	return rows
+}
+
+// writeMaltegoTable writes the Maltego entity table to fileName as CSV
+func writeMaltegoTable(results FinalResult, domain, fileName string) error {
+	return writeCsvFile(fileName, buildMaltegoTable(results, domain))
+}
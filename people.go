@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// placeholder and built-in account names that Office, Windows and
+// friends fill in when nobody bothered to set them
+var builtinAccounts = []string{
+	"administrator",
+	"admin",
+	"guest",
+	"user",
+	"users",
+	"owner",
+	"author",
+	"unknown",
+	"public",
+	"default",
+	"default user",
+	"all users",
+	"windows user",
+	"microsoft office user",
+	"microsoft account",
+	"preferred customer",
+	"valued customer",
+	"registered user",
+	"authorised user",
+	"authorized user",
+	"office user",
+	"test",
+	"temp",
+	"system",
+}
+
+// shared mailboxes rather than people
+var roleMailboxes = []string{
+	"info",
+	"admin",
+	"administrator",
+	"enquiries",
+	"inquiries",
+	"sales",
+	"support",
+	"help",
+	"helpdesk",
+	"hr",
+	"jobs",
+	"careers",
+	"recruitment",
+	"marketing",
+	"press",
+	"media",
+	"contact",
+	"office",
+	"reception",
+	"accounts",
+	"finance",
+	"billing",
+	"noreply",
+	"no-reply",
+	"webmaster",
+	"postmaster",
+	"hostmaster",
+	"abuse",
+	"security",
+	"privacy",
+	"dpo",
+	"team",
+}
+
+// isBuiltinAccount returns true for placeholder names and built-in
+// accounts that aren't real people
+func isBuiltinAccount(s string) bool {
+	return slices.Contains(builtinAccounts, strings.ToLower(strings.TrimSpace(s)))
+}
+
+// normaliseName turns "smith, JOHN" and "john smith" into "John Smith".
+// Middle names are kept. Returns "" if it doesn't look like a name.
+func normaliseName(n string) string {
+	if _, _, ok := splitName(n); !ok {
+		return ""
+	}
+
+	var parts []string
+	if before, after, found := strings.Cut(n, ","); found {
+		parts = append(strings.Fields(after), strings.Fields(before)...)
+	} else {
+		parts = strings.Fields(n)
+	}
+	for i, part := range parts {
+		parts[i] = titleCase(part)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// titleCase capitalises each hyphen or apostrophe separated bit of s,
+// so "o'neil-JONES" becomes "O'Neil-Jones"
+func titleCase(s string) string {
+	runes := []rune(strings.ToLower(s))
+	upperNext := true
+	for i, c := range runes {
+		if upperNext {
+			runes[i] = []rune(strings.ToUpper(string(c)))[0]
+		}
+		upperNext = c == '-' || c == '\''
+	}
+	return string(runes)
+}
+
+// buildPeople merges names, usernames and emails that refer to the
+// same person into one entry each, using the naming formats to match
+// usernames and email local parts to names.
+func buildPeople(results FinalResult) []Person {
+	var people []*Person
+	// first and last name for each person, if they have one
+	var keys [][2]string
+
+	addSource := func(p *Person, evidence, fileName string) {
+		if !slices.Contains(p.Evidence, evidence) {
+			p.Evidence = append(p.Evidence, evidence)
+		}
+		if fileName != "" && !slices.Contains(p.Documents, fileName) {
+			p.Documents = append(p.Documents, fileName)
+		}
+	}
+
+	// findByName returns the person with this first and last name
+	findByName := func(first, last string) *Person {
+		for i, key := range keys {
+			if key == [2]string{first, last} {
+				return people[i]
+			}
+		}
+		return nil
+	}
+
+	// findByHandle returns whoever a username or email local part
+	// belongs to, based on the naming formats
+	findByHandle := func(handle string) *Person {
+		for i, key := range keys {
+			if key[0] == "" {
+				continue
+			}
+			for _, format := range usernameFormats {
+				// first/last on their own are too loose to match on
+				if format.name == "first" || format.name == "last" {
+					continue
+				}
+				if format.build(key[0], key[1]) == handle {
+					return people[i]
+				}
+			}
+		}
+		for _, p := range people {
+			if slices.Contains(p.Usernames, handle) {
+				return p
+			}
+		}
+		return nil
+	}
+
+	newPerson := func(name string, first, last string) *Person {
+		p := &Person{Name: name}
+		people = append(people, p)
+		keys = append(keys, [2]string{first, last})
+		return p
+	}
+
+	// names first - these anchor everything else
+	var handles []Username
+	for _, n := range results.Names {
+		if isBuiltinAccount(n.Name) {
+			continue
+		}
+		first, last, ok := splitName(n.Name)
+		if !ok {
+			// "JSmith" in a name field is a username really
+			if !strings.Contains(strings.TrimSpace(n.Name), " ") && strings.TrimSpace(n.Name) != "" {
+				handles = append(handles, Username{UserName: n.Name, FileName: n.FileName, FileUrl: n.FileUrl})
+			}
+			continue
+		}
+		p := findByName(first, last)
+		if p == nil {
+			p = newPerson(normaliseName(n.Name), first, last)
+		}
+		addSource(p, fmt.Sprintf("name \"%s\" in \"%s\"", n.Name, n.FileName), n.FileName)
+	}
+
+	// then usernames, matched to names where we can
+	handles = append(handles, results.Usernames...)
+	for _, u := range handles {
+		username := strings.ToLower(strings.TrimSpace(u.UserName))
+		// strip any DOMAIN\ prefix
+		if _, after, found := strings.Cut(username, `\`); found {
+			username = after
+		}
+		if username == "" || isBuiltinAccount(username) {
+			continue
+		}
+		p := findByHandle(username)
+		if p == nil {
+			p = newPerson("", "", "")
+		}
+		if !slices.Contains(p.Usernames, username) {
+			p.Usernames = append(p.Usernames, username)
+		}
+		addSource(p, fmt.Sprintf("username \"%s\" in \"%s\"", u.UserName, u.FileName), u.FileName)
+	}
+
+	// finally emails
+	for _, e := range results.Emails {
+		email := strings.ToLower(e.EmailAddr)
+		local, _, found := strings.Cut(email, "@")
+		if !found || slices.Contains(roleMailboxes, local) || isBuiltinAccount(local) {
+			continue
+		}
+		p := findByHandle(local)
+		if p == nil {
+			// john.smith@ gives us a name even if nothing else did
+			if first, last, ok := splitName(strings.NewReplacer(".", " ", "_", " ").Replace(local)); ok && strings.ContainsAny(local, "._") {
+				if p = findByName(first, last); p == nil {
+					p = newPerson(normaliseName(first+" "+last), first, last)
+				}
+			} else {
+				p = newPerson("", "", "")
+			}
+		}
+		if !slices.Contains(p.Emails, email) {
+			p.Emails = append(p.Emails, email)
+		}
+		addSource(p, fmt.Sprintf("email \"%s\" in \"%s\"", e.EmailAddr, e.FileName), e.FileName)
+	}
+
+	var r []Person
+	for _, p := range people {
+		sort.Strings(p.Usernames)
+		sort.Strings(p.Emails)
+		r = append(r, *p)
+	}
+	// named people first, then alphabetically
+	sort.SliceStable(r, func(i, j int) bool {
+		if (r[i].Name == "") != (r[j].Name == "") {
+			return r[i].Name != ""
+		}
+		return personLabel(r[i]) < personLabel(r[j])
+	})
+
+	return r
+}
+
+// personLabel is the best thing we've got to call someone
+func personLabel(p Person) string {
+	switch {
+	case p.Name != "":
+		return p.Name
+	case len(p.Usernames) > 0:
+		return p.Usernames[0]
+	case len(p.Emails) > 0:
+		return p.Emails[0]
+	}
+	return ""
+}
@@ -1,6 +1,7 @@
 package bing
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,19 +10,40 @@ import (
 
 type Client struct {
 	Token  string
-	Client http.Client
+	Client *http.Client
 }
 
-// NewClient creates a new bing client istance
-func NewClient(token string) *Client {
+// StatusError is an error response from the API, other than a bad key.
+// RetryAfter is the Retry-After header, if there was one.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	RetryAfter string
+}
+
+func (e *StatusError) Error() string {
+	return e.Status
+}
+
+// NewClient creates a new bing client istance. Requests go through
+// client, or http.DefaultClient if it's nil.
+func NewClient(token string, client *http.Client) *Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
 	return &Client{
 		Token:  token,
-		Client: *http.DefaultClient,
+		Client: client,
 	}
 }
 
 // Simple Bing Search function
 func (c *Client) Search(search string) (*BingAnswer, error) {
+	return c.SearchContext(context.Background(), search)
+}
+
+// SearchContext is Search, but gives up when ctx is cancelled
+func (c *Client) SearchContext(ctx context.Context, search string) (*BingAnswer, error) {
 	if len(search) > 1500 {
 		return nil, fmt.Errorf("Query lenght must be < 1500 characters")
 	}
@@ -31,6 +53,7 @@ func (c *Client) Search(search string) (*BingAnswer, error) {
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
 	//set Header
 	req.Header.Add("Ocp-Apim-Subscription-Key", c.Token)
 
@@ -46,6 +69,15 @@ func (c *Client) Search(search string) (*BingAnswer, error) {
 	if err != nil {
 		return nil, err
 	}
+	// bad keys get a proper message from checkBingErrors, but other
+	// errors don't always have a JSON body
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusUnauthorized {
+		return nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			RetryAfter: resp.Header.Get("Retry-After"),
+		}
+	}
 	err = checkBingErrors(body)
 	if err != nil {
 		return nil, err
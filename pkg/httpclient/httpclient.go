@@ -0,0 +1,157 @@
+// Package httpclient builds the HTTP client everything else shares, so
+// document fetches and search API calls go out through the same proxy,
+// with the same user agent, TLS settings and timeouts.
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultUserAgent is a current desktop browser, so we blend in a bit
+// better than Go's default does
+const DefaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+
+// Config is how requests should be made
+type Config struct {
+	// Proxy is an http://, https://, socks5:// or socks5h:// URL. If
+	// it's empty, the usual environment variables are used.
+	Proxy string
+	// UserAgents are picked from at random for each request
+	UserAgents []string
+	// Headers are added to every request
+	Headers http.Header
+	// Insecure skips TLS certificate verification
+	Insecure bool
+	// ConnectTimeout limits how long connecting, including the TLS
+	// handshake, can take
+	ConnectTimeout time.Duration
+	// ReadTimeout limits how long we'll wait for the server to send
+	// anything, so stalled downloads don't hang a worker forever
+	ReadTimeout time.Duration
+}
+
+// New builds a client from cfg
+func New(cfg Config) (*http.Client, error) {
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		proxyUrl, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %s: %w", cfg.Proxy, err)
+		}
+		switch proxyUrl.Scheme {
+		case "http", "https", "socks5":
+		case "socks5h":
+			// Go resolves names through the proxy for socks5
+			// anyway, which is what socks5h means elsewhere
+			proxyUrl.Scheme = "socks5"
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q", proxyUrl.Scheme)
+		}
+		proxy = http.ProxyURL(proxyUrl)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   cfg.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	dial := dialer.DialContext
+	if cfg.ReadTimeout > 0 {
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return &deadlineConn{Conn: conn, timeout: cfg.ReadTimeout}, nil
+		}
+	}
+
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dial,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   cfg.ConnectTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       &tls.Config{InsecureSkipVerify: cfg.Insecure},
+	}
+
+	return &http.Client{
+		Transport: &headerTransport{
+			next:       transport,
+			userAgents: cfg.UserAgents,
+			headers:    cfg.Headers,
+		},
+	}, nil
+}
+
+// ReadUserAgents reads a file of user agents, one per line
+func ReadUserAgents(fileName string) ([]string, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read user agents: %w", err)
+	}
+	var userAgents []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			userAgents = append(userAgents, line)
+		}
+	}
+	if len(userAgents) == 0 {
+		return nil, fmt.Errorf("no user agents in %s", fileName)
+	}
+	return userAgents, nil
+}
+
+// ParseHeader splits a "Name: value" header
+func ParseHeader(header string) (string, string, error) {
+	name, value, ok := strings.Cut(header, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("invalid header %q, expected \"Name: value\"", header)
+	}
+	return name, strings.TrimSpace(value), nil
+}
+
+// headerTransport sets the user agent and extra headers on the way out
+type headerTransport struct {
+	next       http.RoundTripper
+	userAgents []string
+	headers    http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers mustn't change the request they're given
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+	if len(t.userAgents) > 0 && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", t.userAgents[rand.Intn(len(t.userAgents))])
+	}
+	return t.next.RoundTrip(req)
+}
+
+// deadlineConn pushes the read deadline back before every read, so the
+// timeout is how long the connection can sit idle rather than how long
+// the whole download can take
+type deadlineConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *deadlineConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
@@ -8,6 +8,7 @@ package metadataplus
 import (
 	"archive/zip"
 	"bufio"
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"io"
@@ -15,6 +16,9 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+
+	"github.com/redskal/dragonvomit/pkg/netintel"
+	"github.com/redskal/dragonvomit/pkg/secrets"
 )
 
 func GetMetadata(r *zip.Reader) (*MetaData, error) {
@@ -102,39 +106,21 @@ func GetMetadata(r *zip.Reader) (*MetaData, error) {
 
 			fileString := string(fileBytes)
 
-			// grep for hostnames
-			foundHostnames := lookupHostnames(fileString)
-			for _, host := range foundHostnames {
-				if !slices.Contains(metadata.Hostnames, host) {
-					metadata.Hostnames = append(metadata.Hostnames, host)
-				}
-			}
-
-			// grep for users
-			names, users := lookupUsernames(fileString)
-			for _, name := range names {
-				if !slices.Contains(metadata.Names, name) && name != "" {
-					metadata.Names = append(metadata.Names, name)
-				}
-			}
-			for _, user := range users {
-				if !slices.Contains(metadata.Usernames, user) {
-					metadata.Usernames = append(metadata.Usernames, user)
-				}
-			}
+			grepContent(fileString, &metadata)
 
-			// grep for emails - this should cover occurences like
-			// mailto:info@example.com?subject=email%20subject&cc=another@example.com
-			// https://go.dev/play/p/5oNBKo3LGvw
-			emails, _ := grepStringForRegex(fileString, `([a-zA-Z0-9+._-]+@[a-zA-Z0-9._-]+\.[a-zA-Z0-9_-]+)`)
-			for _, email := range emails {
-				if !slices.Contains(metadata.Emails, email) {
-					metadata.Emails = append(metadata.Emails, email)
+			// body text is split across runs, cells and shapes, so
+			// stitch it back together and grep that as well. catches
+			// emails and paths that the raw XML hides from the regexes.
+			if isTextPart(f.Name) {
+				text, err := extractText(bytes.NewReader(fileBytes))
+				if err == nil && text != "" {
+					grepContent(text, &metadata)
 				}
 			}
 
+			// rc has been drained by now, so decode from what we read
 			var n Node
-			if err := xml.NewDecoder(rc).Decode(&n); err != nil {
+			if err := xml.NewDecoder(bytes.NewReader(fileBytes)).Decode(&n); err != nil {
 				continue
 			}
 
@@ -156,6 +142,11 @@ func GetMetadata(r *zip.Reader) (*MetaData, error) {
 				// May contain usernames or hostnames, too.
 				searchForTagAttrValue([]Node{n}, "absPath", "url", &metadata.LastSavedPath)
 
+			case "word/fontTable.xml":
+				// fonts are a decent hint at the OS it was written on
+				searchForTagAttrValue([]Node{n}, "font", "name", &metadata.Fonts)
+			case "xl/styles.xml":
+				searchForTagAttrValue([]Node{n}, "name", "val", &metadata.Fonts)
 			}
 
 			// a @sam_phisher original check...
@@ -238,6 +229,55 @@ func GetMetadata(r *zip.Reader) (*MetaData, error) {
 	return &metadata, nil
 }
 
+// grepContent runs the pattern-based lookups over s, which can be
+// raw XML or text reconstructed from a document body.
+func grepContent(s string, metadata *MetaData) {
+	// grep for hostnames
+	foundHostnames := lookupHostnames(s)
+	for _, host := range foundHostnames {
+		if !slices.Contains(metadata.Hostnames, host) {
+			metadata.Hostnames = append(metadata.Hostnames, host)
+		}
+	}
+
+	// grep for users
+	names, users := lookupUsernames(s)
+	for _, name := range names {
+		if !slices.Contains(metadata.Names, name) && name != "" {
+			metadata.Names = append(metadata.Names, name)
+		}
+	}
+	for _, user := range users {
+		if !slices.Contains(metadata.Usernames, user) {
+			metadata.Usernames = append(metadata.Usernames, user)
+		}
+	}
+
+	// grep for emails - this should cover occurences like
+	// mailto:info@example.com?subject=email%20subject&cc=another@example.com
+	// https://go.dev/play/p/5oNBKo3LGvw
+	emails, _ := grepStringForRegex(s, `([a-zA-Z0-9+._-]+@[a-zA-Z0-9._-]+\.[a-zA-Z0-9_-]+)`)
+	for _, email := range emails {
+		if !slices.Contains(metadata.Emails, email) {
+			metadata.Emails = append(metadata.Emails, email)
+		}
+	}
+
+	// secrets and credentials - API keys, connection strings, etc.
+	for _, secret := range secrets.Find(s) {
+		if !slices.Contains(metadata.Secrets, secret) {
+			metadata.Secrets = append(metadata.Secrets, secret)
+		}
+	}
+
+	// internal IPs, domains, intranet URLs, etc.
+	for _, intel := range netintel.Find(s) {
+		if !slices.Contains(metadata.NetworkIntel, intel) {
+			metadata.NetworkIntel = append(metadata.NetworkIntel, intel)
+		}
+	}
+}
+
 // lookupHostnames will pull hostnames from any UNC paths
 // in s
 func lookupHostnames(s string) (r []string) {
@@ -267,6 +307,17 @@ func lookupUsernames(s string) (names, usernames []string) {
 		".",
 		"<cp:keywords></cp:keywords><dc:description>",
 	}
+	// profile folders that every Windows box has. not users.
+	builtinProfiles := []string{
+		"public",
+		"default",
+		"default user",
+		"defaultuser0",
+		"all users",
+		"localservice",
+		"networkservice",
+		"systemprofile",
+	}
 
 	// herein lies wofty logic
 	for _, regex := range regexes {
@@ -276,7 +327,7 @@ func lookupUsernames(s string) (names, usernames []string) {
 			if len(match) > 0 {
 				// checks for first regex care of Chris
 				// not bothering with the Vista checks because I'm no masochist
-				if !slices.Contains(errorStrings, match[1]) {
+				if !slices.Contains(errorStrings, match[1]) && !slices.Contains(builtinProfiles, strings.ToLower(match[1])) {
 					// contains a space?
 					if strings.Contains(match[1], " ") {
 						// starts with space?
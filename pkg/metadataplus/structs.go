@@ -3,7 +3,11 @@ package metadataplus
 
 import (
 	"encoding/xml"
+	"fmt"
 	"strings"
+
+	"github.com/redskal/dragonvomit/pkg/netintel"
+	"github.com/redskal/dragonvomit/pkg/secrets"
 )
 
 type Node struct {
@@ -34,6 +38,9 @@ type MetaData struct {
 	GrepKeywords   []string
 	HiddenSheets   []string
 	LastSavedPath  []string
+	Fonts          []string
+	Secrets        []secrets.Finding
+	NetworkIntel   []netintel.Finding
 	EmbeddedDocs   bool
 	EmbeddedMedia  bool
 }
@@ -42,6 +49,10 @@ type OfficeCoreProperties struct {
 	XMLName        xml.Name `xml:"coreProperties"`
 	Creator        string   `xml:"creator"`
 	LastModifiedBy string   `xml:"lastModifiedBy"`
+	// W3CDTF timestamps, eg. 2021-03-04T10:11:12Z
+	Created     string `xml:"created"`
+	Modified    string `xml:"modified"`
+	LastPrinted string `xml:"lastPrinted"`
 }
 
 type OfficeAppProperty struct {
@@ -51,8 +62,10 @@ type OfficeAppProperty struct {
 	Version     string   `xml:"AppVersion"`
 }
 
+// AppVersion 16 is shared by 2016, 2019, 2021 and 365, so
+// there's no telling them apart from this alone
 var OfficeVersions = map[string]string{
-	"16": "2016",
+	"16": "2016 or later",
 	"15": "2013",
 	"14": "2010",
 	"12": "2007",
@@ -67,7 +80,8 @@ func (a *OfficeAppProperty) GetMajorVersion() string {
 	}
 	v, ok := OfficeVersions[tokens[0]]
 	if !ok {
-		return "(Version unknown)"
+		// don't guess at anything newer than we know about
+		return fmt.Sprintf("(Version %s)", a.Version)
 	}
 	return v
 }
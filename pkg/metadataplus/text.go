@@ -0,0 +1,104 @@
+package metadataplus
+
+import (
+	"encoding/xml"
+	"io"
+	"path"
+	"strings"
+)
+
+// textParts are the globs for parts of an Office document that hold
+// body text people actually typed.
+var textParts = []string{
+	// Word - body, headers/footers, notes and comments
+	"word/document.xml",
+	"word/header*.xml",
+	"word/footer*.xml",
+	"word/footnotes.xml",
+	"word/endnotes.xml",
+	"word/comments.xml",
+	// Excel - shared strings and inline strings in the sheets
+	"xl/sharedStrings.xml",
+	"xl/worksheets/sheet*.xml",
+	// PowerPoint - slides and speaker notes
+	"ppt/slides/slide*.xml",
+	"ppt/notesSlides/notesSlide*.xml",
+}
+
+// isTextPart returns true if name is a part we can pull body text from
+func isTextPart(name string) bool {
+	for _, pattern := range textParts {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// extractText walks the XML in r and rebuilds the text. Runs within a
+// paragraph (<w:r>, <a:r>) are joined with nothing between them, so
+// "j.smith@" and "example.com" in separate runs come out as one email.
+// Paragraphs, shared strings and cells each end up on their own line.
+func extractText(r io.Reader) (string, error) {
+	var sb strings.Builder
+	var inText bool
+	// cell type of the current Excel cell, so we know if <v> is text
+	var cellType string
+	var inValue bool
+	// saves checking the builder every time a paragraph closes
+	var endsInNewline bool
+
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return sb.String(), err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "v":
+				inValue = true
+			case "c":
+				cellType = ""
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "t" {
+						cellType = attr.Value
+					}
+				}
+			case "tab":
+				sb.WriteString("\t")
+				endsInNewline = false
+			case "br", "cr":
+				sb.WriteString("\n")
+				endsInNewline = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "v":
+				inValue = false
+			case "p", "si", "c":
+				if sb.Len() > 0 && !endsInNewline {
+					sb.WriteString("\n")
+					endsInNewline = true
+				}
+			}
+		case xml.CharData:
+			// formula results are the only <v> values that are text.
+			// the rest are numbers or shared string indexes.
+			if (inText || (inValue && cellType == "str")) && len(t) > 0 {
+				sb.Write(t)
+				endsInNewline = t[len(t)-1] == '\n'
+			}
+		}
+	}
+
+	return sb.String(), nil
+}
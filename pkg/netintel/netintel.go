@@ -0,0 +1,105 @@
+/*
+ * Finds references to the target's internal network in content
+ * pulled from documents: private IPs, internal domain names,
+ * intranet URLs and the like. Useful for working out what the
+ * inside looks like before you get there.
+ */
+package netintel
+
+import (
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Finding is a single piece of network intel
+type Finding struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+var (
+	ipv4Regex = regexp.MustCompile(`\b((?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])(?:\.(?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])){3})\b`)
+	// ULAs are fc00::/7, so they start with fc or fd
+	ipv6UlaRegex = regexp.MustCompile(`(?i)\b(f[cd][0-9a-f]{2}(?::[0-9a-f]{0,4}){2,7})`)
+	// well-known internal suffixes
+	internalFqdnRegex = regexp.MustCompile(`(?i)\b((?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+(?:local|corp|internal|intranet|lan|localdomain|private|home\.arpa))\b`)
+	// AD-looking sub-domains of a public domain, eg. dc01.corp.example.com
+	adDomainRegex = regexp.MustCompile(`(?i)\b((?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)*(?:ad|corp|internal|intranet|int|dc|ds|domain)\.[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.[a-z]{2,}(?:\.[a-z]{2})?)\b`)
+	// http://intranet/ style URLs with no dots in the host
+	singleLabelUrlRegex = regexp.MustCompile(`(?i)\bhttps?://([a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?)(?::[0-9]+)?(?:[/?#][^\s"'<>]*)?(?:[\s"'<>]|$)`)
+	// directory services and file shares
+	internalUriRegex = regexp.MustCompile(`(?i)\b((?:ldaps?|smb|cifs|nfs)://[^\s"'<>]+)`)
+)
+
+// Find runs all the network intel checks over s and returns
+// unique findings.
+func Find(s string) (r []Finding) {
+	add := func(findingType, value string) {
+		f := Finding{Type: findingType, Value: value}
+		if !slices.Contains(r, f) {
+			r = append(r, f)
+		}
+	}
+
+	for _, loc := range ipv4Regex.FindAllStringSubmatchIndex(s, -1) {
+		// \b is happy with a dot either side, so make sure this isn't
+		// part of a longer dotted number, like a version
+		start, end := loc[2], loc[3]
+		if (start > 1 && s[start-1] == '.' && isDigit(s[start-2])) || (end+1 < len(s) && s[end] == '.' && isDigit(s[end+1])) {
+			continue
+		}
+		addr, err := netip.ParseAddr(s[start:end])
+		if err == nil && addr.IsPrivate() {
+			add("private-ipv4", s[start:end])
+		}
+	}
+
+	for _, match := range ipv6UlaRegex.FindAllStringSubmatch(s, -1) {
+		addr, err := netip.ParseAddr(match[1])
+		if err == nil && addr.Is6() && addr.IsPrivate() {
+			add("ipv6-ula", strings.ToLower(match[1]))
+		}
+	}
+
+	for _, loc := range internalFqdnRegex.FindAllStringSubmatchIndex(s, -1) {
+		// no lookaheads in Go, so make sure we've not just matched
+		// the start of a longer name like dc01.corp.example.com
+		if end := loc[3]; end+1 < len(s) && s[end] == '.' && isLabelChar(s[end+1]) {
+			continue
+		}
+		add("internal-fqdn", strings.ToLower(s[loc[2]:loc[3]]))
+	}
+
+	for _, match := range adDomainRegex.FindAllStringSubmatch(s, -1) {
+		domain := strings.ToLower(match[1])
+		// don't double up on anything the suffix check caught
+		if !slices.Contains(r, Finding{Type: "internal-fqdn", Value: domain}) {
+			add("ad-domain", domain)
+		}
+	}
+
+	for _, match := range singleLabelUrlRegex.FindAllStringSubmatch(s, -1) {
+		if strings.EqualFold(match[1], "localhost") {
+			continue
+		}
+		add("intranet-url", strings.TrimRight(match[0], " \t\r\n\"'<>"))
+	}
+
+	for _, match := range internalUriRegex.FindAllStringSubmatch(s, -1) {
+		add("internal-uri", match[1])
+	}
+
+	return
+}
+
+// isDigit returns true if c is 0-9
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isLabelChar returns true if c can appear in a DNS label
+func isLabelChar(c byte) bool {
+	return c == '-' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,99 @@
+package netintel
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Finding
+	}{
+		{
+			name:  "private ipv4",
+			input: "server at 192.168.1.10, backup 10.0.0.5.",
+			want: []Finding{
+				{Type: "private-ipv4", Value: "192.168.1.10"},
+				{Type: "private-ipv4", Value: "10.0.0.5"},
+			},
+		},
+		{
+			name:  "public ipv4",
+			input: "dns 8.8.8.8",
+		},
+		{
+			name:  "longer dotted number",
+			input: "192.168.1.1.5",
+		},
+		{
+			name:  "version string",
+			input: "build 1.10.0.0.1 and v2.172.16.0.1",
+		},
+		{
+			name:  "out of range octet",
+			input: "10.0.0.256",
+		},
+		{
+			name:  "ipv6 ula",
+			input: "addr FD12:3456:789A::1",
+			want:  []Finding{{Type: "ipv6-ula", Value: "fd12:3456:789a::1"}},
+		},
+		{
+			name:  "internal fqdn",
+			input: `\\FS01.Corp.Local\share`,
+			want:  []Finding{{Type: "internal-fqdn", Value: "fs01.corp.local"}},
+		},
+		{
+			name:  "internal suffix mid-name",
+			input: "dc01.corp.example.com",
+			want:  []Finding{{Type: "ad-domain", Value: "dc01.corp.example.com"}},
+		},
+		{
+			name:  "ad domain",
+			input: "joined to ad.example.co.uk",
+			want:  []Finding{{Type: "ad-domain", Value: "ad.example.co.uk"}},
+		},
+		{
+			name:  "public domain",
+			input: "www.example.com",
+		},
+		{
+			name:  "single label url",
+			input: `<a href="http://intranet/hr/policies">`,
+			want:  []Finding{{Type: "intranet-url", Value: "http://intranet/hr/policies"}},
+		},
+		{
+			name:  "single label url with port",
+			input: "see https://wiki:8443 for more",
+			want:  []Finding{{Type: "intranet-url", Value: "https://wiki:8443"}},
+		},
+		{
+			name:  "localhost url",
+			input: "http://localhost:8080/",
+		},
+		{
+			name:  "dotted url",
+			input: "http://www.example.com/",
+		},
+		{
+			name:  "internal uri",
+			input: "ldap://dc01/DC=corp",
+			want:  []Finding{{Type: "internal-uri", Value: "ldap://dc01/DC=corp"}},
+		},
+		{
+			name:  "duplicates reported once",
+			input: "10.1.1.1 and 10.1.1.1",
+			want:  []Finding{{Type: "private-ipv4", Value: "10.1.1.1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Find(tt.input); !slices.Equal(got, tt.want) {
+				t.Errorf("Find(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
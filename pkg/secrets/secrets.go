@@ -0,0 +1,198 @@
+/*
+ * Detectors for secrets and credentials that people leave lying
+ * around in documents. Each detector is a regex, optionally with a
+ * Shannon entropy floor to weed out the "password=password" style
+ * noise that template documents are full of.
+ */
+package secrets
+
+import (
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Finding is a single secret pulled out of some content.
+type Finding struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type detector struct {
+	name       string
+	re         *regexp.Regexp
+	minEntropy float64
+	// group is the submatch that gets entropy checked. the full
+	// match is always what's reported.
+	group int
+}
+
+var detectors = []detector{
+	{
+		name:       "aws-access-key",
+		re:         regexp.MustCompile(`\b((?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[0-9A-Z]{16})\b`),
+		minEntropy: 3.0,
+		group:      1,
+	},
+	{
+		name:       "azure-storage-connection-string",
+		re:         regexp.MustCompile(`DefaultEndpointsProtocol=https?;AccountName=[a-z0-9]+;AccountKey=([A-Za-z0-9+/]{40,}={0,2})(?:;EndpointSuffix=[a-zA-Z0-9.]+)?`),
+		minEntropy: 4.0,
+		group:      1,
+	},
+	{
+		name: "private-key",
+		re:   regexp.MustCompile(`(?s)-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY-----.+?-----END (?:[A-Z0-9]+ )*PRIVATE KEY-----`),
+	},
+	{
+		name:       "jwt",
+		re:         regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.([A-Za-z0-9_-]{10,})`),
+		minEntropy: 3.5,
+		group:      1,
+	},
+	{
+		name:       "slack-token",
+		re:         regexp.MustCompile(`\bxox[abposr]-([0-9A-Za-z-]{10,})`),
+		minEntropy: 3.0,
+		group:      1,
+	},
+	{
+		name: "slack-webhook",
+		re:   regexp.MustCompile(`https://hooks\.slack\.com/services/T[A-Z0-9]+/B[A-Z0-9]+/[A-Za-z0-9]{20,}`),
+	},
+	{
+		name:       "github-token",
+		re:         regexp.MustCompile(`\b(?:gh[pousr]_([A-Za-z0-9]{36,})|github_pat_([A-Za-z0-9_]{22,}))`),
+		minEntropy: 3.5,
+		group:      1,
+	},
+	{
+		name:       "basic-auth-url",
+		re:         regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.-]*://[^\s:@/"'<>]+:([^\s@/"'<>]+)@[^\s/"'<>]+`),
+		minEntropy: 1.5,
+		group:      1,
+	},
+	{
+		name:       "password-pair",
+		re:         regexp.MustCompile(`(?i)\b(?:password|passwd|pwd)\s*=\s*([^;"'<>\s&]+)`),
+		minEntropy: 2.0,
+		group:      1,
+	},
+}
+
+// placeholders are values that turn up in templates and docs
+// far more often than real credentials do.
+var placeholders = []string{
+	"password",
+	"passwd",
+	"pass",
+	"pwd",
+	"secret",
+	"changeme",
+	"yourpassword",
+	"your_password",
+	"mypassword",
+	"example",
+	"xxxxxx",
+	"null",
+	"none",
+}
+
+// Find runs all detectors over s and returns unique findings.
+func Find(s string) (r []Finding) {
+	for _, d := range detectors {
+		for _, match := range d.re.FindAllStringSubmatch(s, -1) {
+			if d.minEntropy > 0 {
+				candidate := match[0]
+				// github has two styles of token, so take whichever
+				// group actually matched
+				for i := d.group; i < len(match); i++ {
+					if match[i] != "" {
+						candidate = match[i]
+						break
+					}
+				}
+				if isPlaceholder(candidate) || ShannonEntropy(candidate) < d.minEntropy {
+					continue
+				}
+			}
+
+			f := Finding{Type: d.name, Value: match[0]}
+			if !slices.Contains(r, f) {
+				r = append(r, f)
+			}
+		}
+	}
+
+	return
+}
+
+// ShannonEntropy returns the entropy of s in bits per character.
+func ShannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+
+	counts := make(map[rune]int)
+	total := 0
+	for _, c := range s {
+		counts[c]++
+		total++
+	}
+
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+
+	return entropy
+}
+
+// isPlaceholder checks for obvious dummy values, including
+// masked ones like "********" and templated ones like "{password}"
+func isPlaceholder(s string) bool {
+	lower := strings.ToLower(s)
+	if slices.Contains(placeholders, lower) {
+		return true
+	}
+	if strings.Trim(lower, "*x.") == "" {
+		return true
+	}
+	if strings.HasPrefix(lower, "{") || strings.HasPrefix(lower, "$") || strings.HasPrefix(lower, "%") || strings.HasPrefix(lower, "<") {
+		return true
+	}
+
+	return false
+}
+
+// Redact returns f.Value masked according to mode. "none" shows
+// everything, "full" hides everything, and "partial" (or anything
+// else) keeps just enough to recognise the secret.
+func (f Finding) Redact(mode string) string {
+	switch mode {
+	case "none":
+		return f.Value
+	case "full":
+		return "[REDACTED]"
+	}
+
+	// private keys are multi-line, so the header will do
+	if f.Type == "private-key" {
+		header, _, _ := strings.Cut(f.Value, "\n")
+		return strings.TrimSpace(header) + " [REDACTED]"
+	}
+
+	runes := []rune(f.Value)
+	if len(runes) <= 8 {
+		return strings.Repeat("*", len(runes))
+	}
+
+	// show a quarter of it at most, split between the ends
+	keep := len(runes) / 8
+	if keep > 6 {
+		keep = 6
+	}
+	return string(runes[:keep]) + strings.Repeat("*", len(runes)-keep*2) + string(runes[len(runes)-keep:])
+}
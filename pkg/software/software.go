@@ -0,0 +1,229 @@
+/*
+ * Normalises the free-text software strings found in document
+ * metadata (PDF Creator/Producer, Office Application/AppVersion)
+ * into vendor, product, version and OS, and checks them against
+ * a bundled table of end-of-life dates. Everything is offline.
+ */
+package software
+
+import (
+	_ "embed"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Info is a normalised software string
+type Info struct {
+	Vendor  string `json:"vendor,omitempty"`
+	Product string `json:"product,omitempty"`
+	Version string `json:"version,omitempty"`
+	OS      string `json:"os,omitempty"`
+	Raw     string `json:"raw,omitempty"`
+}
+
+// vendorHint maps a keyword in the product name to its vendor
+type vendorHint struct {
+	keyword string
+	vendor  string
+}
+
+// checked in order, first match wins
+var vendorHints = []vendorHint{
+	{"libreoffice", "The Document Foundation"},
+	{"openoffice", "Apache"},
+	{"word", "Microsoft"},
+	{"excel", "Microsoft"},
+	{"powerpoint", "Microsoft"},
+	{"publisher", "Microsoft"},
+	{"visio", "Microsoft"},
+	{"outlook", "Microsoft"},
+	{"office", "Microsoft"},
+	{"print to pdf", "Microsoft"},
+	{"reporting services", "Microsoft"},
+	{"pscript", "Microsoft"},
+	{"acrobat", "Adobe"},
+	{"distiller", "Adobe"},
+	{"indesign", "Adobe"},
+	{"photoshop", "Adobe"},
+	{"illustrator", "Adobe"},
+	{"framemaker", "Adobe"},
+	{"pdf library", "Adobe"},
+	{"pdfmaker", "Adobe"},
+	{"quartz", "Apple"},
+	{"pages", "Apple"},
+	{"keynote", "Apple"},
+	{"numbers", "Apple"},
+	{"ghostscript", "Artifex"},
+	{"itext", "iText Group"},
+	{"skia", "Google"},
+	{"google docs", "Google"},
+	{"nitro", "Nitro"},
+	{"foxit", "Foxit"},
+	{"pdfcreator", "pdfforge"},
+	{"wkhtmltopdf", "wkhtmltopdf"},
+	{"pdftex", "TeX"},
+	{"xetex", "TeX"},
+	{"crystal reports", "SAP"},
+}
+
+var (
+	// trademark clutter
+	trademarkRegex = regexp.MustCompile(`(?i)®|™|\(R\)|\(TM\)`)
+	// "(Windows)", "(Macintosh)" after Adobe products
+	osParenRegex = regexp.MustCompile(`(?i)\((Windows|Macintosh|Mac OS X[^)]*|macOS[^)]*|Linux|X11|Unix)\)`)
+	// "Mac OS X 10.15.7 Quartz PDFContext", "macOS Version 11.2 (Build 20D64) Quartz PDFContext"
+	macOSRegex = regexp.MustCompile(`(?i)^(?:Mac OS X|macOS)(?: Version)? ([0-9]+(?:\.[0-9]+)*)(?: \(Build [^)]+\))?\s*`)
+	// versions come in a few styles: 2016, CS6, CC 2017, DC, 365, 9.0
+	versionRegex = regexp.MustCompile(`(?i)\b((?:19|20)[0-9]{2}|CS[0-9]?|CC(?: (?:19|20)[0-9]{2})?|DC|365|[0-9]+(?:\.[0-9]+)*|X|XI)\b`)
+	// subscription Office has no version number, just this
+	office365Regex = regexp.MustCompile(`(?i)\s+for (?:Microsoft|Office) 365`)
+	// "Version 5.2.2", "(Version 17.0000)" - the word just gets in the way
+	versionWordRegex = regexp.MustCompile(`(?i)\s*\(?\bversion\b(?:\s+unknown\)?)?\s*`)
+	// "by 1T3XT", etc. add nothing
+	suffixRegex = regexp.MustCompile(`(?i)\s+(?:by .*|- .*)$`)
+)
+
+// Parse normalises raw into an Info
+func Parse(raw string) Info {
+	info := Info{Raw: raw}
+	s := strings.TrimSpace(trademarkRegex.ReplaceAllString(raw, ""))
+	s = strings.Join(strings.Fields(s), " ")
+
+	// work out the OS first and strip it out
+	if match := macOSRegex.FindStringSubmatch(s); match != nil {
+		info.OS = "macOS " + match[1]
+		s = strings.TrimPrefix(s, match[0])
+		if strings.Contains(strings.ToLower(s), "quartz") {
+			// the version is the OS version, not Quartz's
+			info.Vendor = "Apple"
+			info.Product = "Quartz PDFContext"
+			info.Version = match[1]
+			return info
+		}
+	}
+	if match := osParenRegex.FindStringSubmatch(s); match != nil {
+		info.OS = normaliseOS(match[1])
+		s = strings.TrimSpace(strings.Replace(s, match[0], "", 1))
+	}
+	if strings.Contains(strings.ToLower(s), "for mac") {
+		info.OS = "macOS"
+	}
+	if office365Regex.MatchString(s) {
+		s = office365Regex.ReplaceAllString(s, " 365")
+	}
+	s = versionWordRegex.ReplaceAllString(s, " ")
+	s = strings.TrimSpace(strings.TrimSuffix(suffixRegex.ReplaceAllString(s, ""), ")"))
+
+	// version is the first version-looking thing, the product is
+	// whatever came before it
+	if loc := versionRegex.FindStringSubmatchIndex(s); loc != nil {
+		info.Version = s[loc[2]:loc[3]]
+		// "2016 or later" isn't 2016, so don't let it look like it
+		if rest := strings.ToLower(s[loc[1]:]); strings.HasPrefix(rest, "+") || strings.HasPrefix(rest, " or later") {
+			info.Version += "+"
+		}
+		info.Product = strings.TrimSpace(s[:loc[0]])
+		if info.Product == "" {
+			// eg. "1.4 PDF Library" - unlikely, but just in case
+			info.Product = strings.TrimSpace(s[loc[1]:])
+		}
+	} else {
+		info.Product = s
+	}
+	info.Product = strings.Trim(info.Product, " -:,;(")
+
+	// vendor from the product name, then strip the vendor out of it
+	lower := strings.ToLower(info.Product)
+	for _, hint := range vendorHints {
+		if strings.Contains(lower, hint.keyword) {
+			info.Vendor = hint.vendor
+			break
+		}
+	}
+	if info.Vendor != "" {
+		info.Product = strings.TrimSpace(strings.TrimPrefix(info.Product, info.Vendor))
+		info.Product = strings.TrimPrefix(info.Product, ": ")
+	}
+	// "Microsoft Office Word" and "Microsoft Word" are the same thing
+	info.Product = strings.TrimPrefix(info.Product, "Office ")
+	if info.Product == "" && info.Vendor == "Microsoft" {
+		info.Product = "Office"
+	}
+
+	// things that only run on one platform
+	if info.OS == "" {
+		switch {
+		case strings.Contains(lower, "pscript5"), strings.Contains(lower, "print to pdf"):
+			info.OS = "Windows"
+		case info.Vendor == "Apple":
+			info.OS = "macOS"
+		}
+	}
+
+	return info
+}
+
+// normaliseOS tidies up the OS names found in software strings
+func normaliseOS(s string) string {
+	lower := strings.ToLower(s)
+	switch {
+	case strings.HasPrefix(lower, "windows"):
+		return "Windows"
+	case strings.HasPrefix(lower, "mac"):
+		return "macOS"
+	case lower == "linux", lower == "x11", lower == "unix":
+		return "Linux/Unix"
+	}
+	return s
+}
+
+// eolEntry is a row in the bundled end-of-life table
+type eolEntry struct {
+	Vendor  string `json:"vendor"`
+	Product string `json:"product"` // prefix match, case-insensitive
+	Version string `json:"version"` // matches the version or anything under it
+	EOL     string `json:"eol"`     // YYYY-MM-DD
+}
+
+//go:embed eol.json
+var eolJson []byte
+
+var eolTable []eolEntry
+
+func init() {
+	// it's bundled, so if it doesn't parse it's a build problem
+	if err := json.Unmarshal(eolJson, &eolTable); err != nil {
+		panic("software: bad eol.json: " + err.Error())
+	}
+}
+
+// EndOfLife returns the end-of-life date for i, and whether that
+// date has passed. Returns an empty date if we don't know.
+func (i Info) EndOfLife() (string, bool) {
+	if i.Version == "" {
+		return "", false
+	}
+
+	for _, entry := range eolTable {
+		if !strings.EqualFold(entry.Vendor, i.Vendor) {
+			continue
+		}
+		if !strings.HasPrefix(strings.ToLower(i.Product), strings.ToLower(entry.Product)) {
+			continue
+		}
+		// "10.15" covers 10.15.7, "9" covers 9.0, etc.
+		if !strings.EqualFold(entry.Version, i.Version) && !strings.HasPrefix(i.Version, entry.Version+".") {
+			continue
+		}
+
+		eol, err := time.Parse("2006-01-02", entry.EOL)
+		if err != nil {
+			return entry.EOL, false
+		}
+		return entry.EOL, time.Now().After(eol)
+	}
+
+	return "", false
+}
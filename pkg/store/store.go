@@ -0,0 +1,314 @@
+// Package store keeps documents, their sources and findings in a
+// per-engagement SQLite database, so repeated runs against a target
+// build on each other instead of starting from scratch.
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	domain      TEXT NOT NULL,
+	extensions  TEXT NOT NULL,
+	started_at  TEXT NOT NULL,
+	finished_at TEXT
+);
+
+CREATE TABLE IF NOT EXISTS documents (
+	id               INTEGER PRIMARY KEY AUTOINCREMENT,
+	url              TEXT NOT NULL UNIQUE,
+	file_name        TEXT NOT NULL DEFAULT '',
+	file_type        TEXT NOT NULL DEFAULT '',
+	status           TEXT NOT NULL DEFAULT 'pending',
+	error            TEXT NOT NULL DEFAULT '',
+	http_status      INTEGER NOT NULL DEFAULT 0,
+	content_type     TEXT NOT NULL DEFAULT '',
+	size             INTEGER NOT NULL DEFAULT 0,
+	sha256           TEXT NOT NULL DEFAULT '',
+	creator          TEXT NOT NULL DEFAULT '',
+	last_modified_by TEXT NOT NULL DEFAULT '',
+	created          TEXT NOT NULL DEFAULT '',
+	modified         TEXT NOT NULL DEFAULT '',
+	printed          TEXT NOT NULL DEFAULT '',
+	fetched_at       TEXT NOT NULL DEFAULT '',
+	duplicate_of     TEXT NOT NULL DEFAULT '',
+	app_version      TEXT NOT NULL DEFAULT '',
+	first_run        INTEGER REFERENCES runs(id),
+	last_run         INTEGER REFERENCES runs(id)
+);
+
+CREATE TABLE IF NOT EXISTS sources (
+	document_id   INTEGER NOT NULL REFERENCES documents(id),
+	search_engine TEXT NOT NULL,
+	crawled       TEXT NOT NULL DEFAULT '',
+	first_run     INTEGER REFERENCES runs(id),
+	last_run      INTEGER REFERENCES runs(id),
+	UNIQUE (document_id, search_engine)
+);
+
+CREATE TABLE IF NOT EXISTS findings (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	document_id INTEGER NOT NULL REFERENCES documents(id),
+	type        TEXT NOT NULL,
+	subtype     TEXT NOT NULL DEFAULT '',
+	value       TEXT NOT NULL DEFAULT '',
+	first_run   INTEGER REFERENCES runs(id),
+	UNIQUE (document_id, type, subtype, value)
+);
+
+CREATE INDEX IF NOT EXISTS findings_type ON findings (type, value);
+`
+
+// StatusPending is a document we've found but not fetched yet
+const StatusPending = "pending"
+
+// Store is an open project database
+type Store struct {
+	db *sql.DB
+}
+
+// Document is everything we know about a fetched document, apart from
+// its findings
+type Document struct {
+	URL            string
+	FileName       string
+	FileType       string
+	Status         string
+	Error          string
+	HTTPStatus     int
+	ContentType    string
+	Size           int64
+	SHA256         string
+	Creator        string
+	LastModifiedBy string
+	Created        time.Time
+	Modified       time.Time
+	Printed        time.Time
+	FetchedAt      time.Time
+	DuplicateOf    string
+	AppVersion     string
+}
+
+// Finding is a single value found in a document
+type Finding struct {
+	Type    string
+	Subtype string
+	Value   string
+}
+
+// Open opens the database at fileName, creating it and its tables if
+// needed
+func Open(fileName string) (*Store, error) {
+	db, err := sql.Open("sqlite", fileName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open project database: %w", err)
+	}
+	// one writer at a time keeps SQLite happy
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to create project database tables: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// StartRun records the start of a run and returns its ID
+func (s *Store) StartRun(domain, extensions string) (int64, error) {
+	res, err := s.db.Exec(`INSERT INTO runs (domain, extensions, started_at) VALUES (?, ?, ?)`,
+		domain, extensions, formatTime(time.Now()))
+	if err != nil {
+		return 0, fmt.Errorf("unable to record run: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// FinishRun records the end of a run
+func (s *Store) FinishRun(runId int64) error {
+	if _, err := s.db.Exec(`UPDATE runs SET finished_at = ? WHERE id = ?`, formatTime(time.Now()), runId); err != nil {
+		return fmt.Errorf("unable to finish run: %w", err)
+	}
+	return nil
+}
+
+// AddSource records that searchEngine returned url in this run, adding
+// the document as pending if we've not seen it before
+func (s *Store) AddSource(runId int64, url, fileName, searchEngine string, crawled time.Time) error {
+	docId, err := s.documentId(runId, url, fileName)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`INSERT INTO sources (document_id, search_engine, crawled, first_run, last_run)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (document_id, search_engine) DO UPDATE SET
+			last_run = excluded.last_run,
+			crawled = CASE WHEN excluded.crawled != '' THEN excluded.crawled ELSE crawled END`,
+		docId, searchEngine, formatTime(crawled), runId, runId)
+	if err != nil {
+		return fmt.Errorf("unable to record source for %s: %w", url, err)
+	}
+	return nil
+}
+
+// Status returns how fetching url went last time, and the HTTP status
+// we got. Documents we've never seen are pending.
+func (s *Store) Status(url string) (string, int, error) {
+	var status string
+	var httpStatus int
+	err := s.db.QueryRow(`SELECT status, http_status FROM documents WHERE url = ?`, url).Scan(&status, &httpStatus)
+	if errors.Is(err, sql.ErrNoRows) {
+		return StatusPending, 0, nil
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("unable to look up %s: %w", url, err)
+	}
+	return status, httpStatus, nil
+}
+
+// SaveDocument records the outcome of fetching and analysing a document,
+// along with anything found in it. Findings accumulate, so nothing
+// found by an earlier run is lost, unless the URL now serves a
+// different document.
+func (s *Store) SaveDocument(runId int64, doc Document, findings []Finding) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("unable to save %s: %w", doc.URL, err)
+	}
+	defer tx.Rollback()
+
+	// findings from the old content don't belong to the new
+	if doc.SHA256 != "" {
+		_, err = tx.Exec(`DELETE FROM findings WHERE document_id IN
+			(SELECT id FROM documents WHERE url = ? AND sha256 != '' AND sha256 != ?)`, doc.URL, doc.SHA256)
+		if err != nil {
+			return fmt.Errorf("unable to clear old findings for %s: %w", doc.URL, err)
+		}
+	}
+
+	var docId int64
+	err = tx.QueryRow(`INSERT INTO documents (url, file_name, file_type, status, error, http_status,
+			content_type, size, sha256, creator, last_modified_by, created, modified, printed,
+			fetched_at, duplicate_of, app_version, first_run, last_run)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (url) DO UPDATE SET
+			file_name = excluded.file_name,
+			file_type = excluded.file_type,
+			status = excluded.status,
+			error = excluded.error,
+			http_status = excluded.http_status,
+			content_type = excluded.content_type,
+			size = excluded.size,
+			-- a failed fetch doesn't tell us the content has changed
+			sha256 = CASE WHEN excluded.sha256 != '' THEN excluded.sha256 ELSE sha256 END,
+			creator = excluded.creator,
+			last_modified_by = excluded.last_modified_by,
+			created = excluded.created,
+			modified = excluded.modified,
+			printed = excluded.printed,
+			fetched_at = excluded.fetched_at,
+			duplicate_of = excluded.duplicate_of,
+			app_version = excluded.app_version,
+			last_run = excluded.last_run
+		RETURNING id`,
+		doc.URL, doc.FileName, doc.FileType, doc.Status, doc.Error, doc.HTTPStatus,
+		doc.ContentType, doc.Size, doc.SHA256, doc.Creator, doc.LastModifiedBy,
+		formatTime(doc.Created), formatTime(doc.Modified), formatTime(doc.Printed),
+		formatTime(doc.FetchedAt), doc.DuplicateOf, doc.AppVersion, runId, runId).Scan(&docId)
+	if err != nil {
+		return fmt.Errorf("unable to save %s: %w", doc.URL, err)
+	}
+
+	for _, f := range findings {
+		_, err := tx.Exec(`INSERT OR IGNORE INTO findings (document_id, type, subtype, value, first_run)
+			VALUES (?, ?, ?, ?, ?)`, docId, f.Type, f.Subtype, f.Value, runId)
+		if err != nil {
+			return fmt.Errorf("unable to save findings for %s: %w", doc.URL, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to save %s: %w", doc.URL, err)
+	}
+	return nil
+}
+
+// Document loads a previously saved document and its findings
+func (s *Store) Document(url string) (Document, []Finding, error) {
+	var doc Document
+	var docId int64
+	var created, modified, printed, fetchedAt string
+	err := s.db.QueryRow(`SELECT id, url, file_name, file_type, status, error, http_status, content_type,
+			size, sha256, creator, last_modified_by, created, modified, printed, fetched_at, duplicate_of,
+			app_version
+		FROM documents WHERE url = ?`, url).Scan(
+		&docId, &doc.URL, &doc.FileName, &doc.FileType, &doc.Status, &doc.Error, &doc.HTTPStatus,
+		&doc.ContentType, &doc.Size, &doc.SHA256, &doc.Creator, &doc.LastModifiedBy,
+		&created, &modified, &printed, &fetchedAt, &doc.DuplicateOf, &doc.AppVersion)
+	if err != nil {
+		return doc, nil, fmt.Errorf("unable to load %s: %w", url, err)
+	}
+	doc.Created = parseTime(created)
+	doc.Modified = parseTime(modified)
+	doc.Printed = parseTime(printed)
+	doc.FetchedAt = parseTime(fetchedAt)
+
+	rows, err := s.db.Query(`SELECT type, subtype, value FROM findings WHERE document_id = ? ORDER BY id`, docId)
+	if err != nil {
+		return doc, nil, fmt.Errorf("unable to load findings for %s: %w", url, err)
+	}
+	defer rows.Close()
+
+	var findings []Finding
+	for rows.Next() {
+		var f Finding
+		if err := rows.Scan(&f.Type, &f.Subtype, &f.Value); err != nil {
+			return doc, nil, fmt.Errorf("unable to load findings for %s: %w", url, err)
+		}
+		findings = append(findings, f)
+	}
+
+	return doc, findings, rows.Err()
+}
+
+// documentId returns the ID for url, adding a pending document if it's
+// new to us
+func (s *Store) documentId(runId int64, url, fileName string) (int64, error) {
+	var docId int64
+	err := s.db.QueryRow(`INSERT INTO documents (url, file_name, first_run, last_run)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (url) DO UPDATE SET last_run = excluded.last_run
+		RETURNING id`, url, fileName, runId, runId).Scan(&docId)
+	if err != nil {
+		return 0, fmt.Errorf("unable to record %s: %w", url, err)
+	}
+	return docId, nil
+}
+
+// formatTime keeps the UTC offset so timezone inference still works on
+// reloaded documents. Zero times are stored as empty strings.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// parseTime undoes formatTime
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
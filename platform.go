@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/redskal/dragonvomit/pkg/software"
+)
+
+// fonts that only really ship with one OS. Calibri and friends come
+// with Office on every platform, so they're no use here.
+var platformFonts = map[string][]string{
+	"macOS": {
+		"helvetica neue",
+		"lucida grande",
+		"menlo",
+		"monaco",
+		"geneva",
+		"avenir",
+		"avenir next",
+		"apple color emoji",
+		".applesystemuifont",
+		".sf ns",
+		"sf pro",
+		"hiragino",
+		"pingfang",
+		"chalkboard",
+		"marker felt",
+	},
+	"Windows": {
+		"segoe ui",
+		"tahoma",
+		"ms mincho",
+		"ms gothic",
+		"ms sans serif",
+		"microsoft yahei",
+		"simsun",
+		"malgun gothic",
+		"marlett",
+	},
+	"Linux": {
+		"dejavu sans",
+		"dejavu serif",
+		"dejavu sans mono",
+		"liberation sans",
+		"liberation serif",
+		"liberation mono",
+		"ubuntu",
+		"cantarell",
+		"freesans",
+		"freeserif",
+		"nimbus sans",
+	},
+}
+
+var (
+	windowsPathRegex = regexp.MustCompile(`(?i)(?:^|[^a-z])[a-z]:\\|^\\\\[^\\]+\\`)
+	macPathRegex     = regexp.MustCompile(`(?:^|file://|[\s"'])/(?:Users|Volumes|Applications)/`)
+	linuxPathRegex   = regexp.MustCompile(`(?:^|file://|[\s"'])/home/[^/]+/`)
+)
+
+// weights for each kind of evidence
+const (
+	weightSoftwareOS = 3.0
+	weightPathStyle  = 2.0
+	weightFont       = 1.0
+	// Windows Office always writes AppVersion as NN.0000, but so do
+	// some older Mac versions, so zeros are only a weak hint
+	weightMacAppVersion     = 2.0
+	weightWindowsAppVersion = 1.0
+)
+
+// inferPlatforms works out the probable OS each document was written
+// on, along with the evidence, then totals them up across the target.
+func inferPlatforms(results FinalResult) ([]Platform, []OSCount) {
+	type docEvidence struct {
+		fileName string
+		scores   map[string]float64
+		evidence []string
+	}
+	docs := make(map[string]*docEvidence)
+	var order []string
+
+	addEvidence := func(fileUrl, fileName, osName string, weight float64, evidence string) {
+		d, ok := docs[fileUrl]
+		if !ok {
+			d = &docEvidence{fileName: fileName, scores: make(map[string]float64)}
+			docs[fileUrl] = d
+			order = append(order, fileUrl)
+		}
+		if !slices.Contains(d.evidence, evidence) {
+			d.evidence = append(d.evidence, evidence)
+			d.scores[osName] += weight
+		}
+	}
+
+	// software strings - PDF producers and Office apps
+	for _, s := range results.Softwares {
+		info := software.Parse(s.Value)
+		osName := osFamily(info.OS)
+		if osName == "" && strings.Contains(strings.ToLower(s.Value), "macintosh") {
+			// Mac Office calls itself "Microsoft Macintosh Word"
+			osName = "macOS"
+		}
+		if osName != "" {
+			addEvidence(s.FileUrl, s.FileName, osName, weightSoftwareOS, fmt.Sprintf("software \"%s\"", s.Value))
+		}
+	}
+
+	// Office AppVersion. Office for Mac 2016 and later fills in the
+	// minor version, eg. 16.0300
+	for _, d := range results.Documents {
+		major, minor, found := strings.Cut(d.AppVersion, ".")
+		if !found || major == "" || minor == "" {
+			continue
+		}
+		if strings.Trim(minor, "0") != "" {
+			addEvidence(d.FileUrl, d.FileName, "macOS", weightMacAppVersion, fmt.Sprintf("AppVersion \"%s\"", d.AppVersion))
+		} else {
+			addEvidence(d.FileUrl, d.FileName, "Windows", weightWindowsAppVersion, fmt.Sprintf("AppVersion \"%s\"", d.AppVersion))
+		}
+	}
+
+	// path styles
+	type pathSource struct{ path, fileName, fileUrl string }
+	var paths []pathSource
+	for _, p := range results.FilePaths {
+		paths = append(paths, pathSource{p.FilePath, p.FileName, p.FileUrl})
+	}
+	for _, p := range results.LastSavedPaths {
+		paths = append(paths, pathSource{p.Path, p.FileName, p.FileUrl})
+	}
+	for _, p := range results.ImageLinks {
+		paths = append(paths, pathSource{p.ImageLink, p.FileName, p.FileUrl})
+	}
+	for _, p := range paths {
+		switch {
+		case macPathRegex.MatchString(p.path):
+			addEvidence(p.fileUrl, p.fileName, "macOS", weightPathStyle, fmt.Sprintf("path \"%s\"", p.path))
+		case linuxPathRegex.MatchString(p.path):
+			addEvidence(p.fileUrl, p.fileName, "Linux", weightPathStyle, fmt.Sprintf("path \"%s\"", p.path))
+		case windowsPathRegex.MatchString(p.path):
+			addEvidence(p.fileUrl, p.fileName, "Windows", weightPathStyle, fmt.Sprintf("path \"%s\"", p.path))
+		}
+	}
+
+	// fonts
+	for _, f := range results.Fonts {
+		font := strings.ToLower(f.Font)
+		for osName, fonts := range platformFonts {
+			if slices.Contains(fonts, font) {
+				addEvidence(f.FileUrl, f.FileName, osName, weightFont, fmt.Sprintf("font \"%s\"", f.Font))
+			}
+		}
+	}
+
+	var platforms []Platform
+	counts := make(map[string]int)
+	for _, fileUrl := range order {
+		d := docs[fileUrl]
+
+		// highest score wins. ties go alphabetically so the output
+		// doesn't change between runs.
+		var best string
+		var bestScore, total float64
+		for osName, score := range d.scores {
+			total += score
+			if score > bestScore || (score == bestScore && osName < best) {
+				best = osName
+				bestScore = score
+			}
+		}
+
+		confidence := "low"
+		switch {
+		case bestScore >= weightSoftwareOS && bestScore == total:
+			confidence = "high"
+		case bestScore >= weightPathStyle && bestScore > total/2:
+			confidence = "medium"
+		}
+
+		platforms = append(platforms, Platform{
+			OS:         best,
+			Confidence: confidence,
+			Evidence:   d.evidence,
+			FileName:   d.fileName,
+			FileUrl:    fileUrl,
+		})
+		counts[best]++
+	}
+
+	var distribution []OSCount
+	for osName, count := range counts {
+		distribution = append(distribution, OSCount{OS: osName, Count: count})
+	}
+	sort.Slice(distribution, func(i, j int) bool {
+		if distribution[i].Count != distribution[j].Count {
+			return distribution[i].Count > distribution[j].Count
+		}
+		return distribution[i].OS < distribution[j].OS
+	})
+
+	return platforms, distribution
+}
+
+// osFamily boils the OS from a software string down to the family
+func osFamily(s string) string {
+	lower := strings.ToLower(s)
+	switch {
+	case lower == "":
+		return ""
+	case strings.HasPrefix(lower, "windows"):
+		return "Windows"
+	case strings.HasPrefix(lower, "macos"), strings.HasPrefix(lower, "mac os"):
+		return "macOS"
+	case strings.HasPrefix(lower, "linux"):
+		return "Linux"
+	}
+	return s
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/redskal/dragonvomit/pkg/netintel"
+	"github.com/redskal/dragonvomit/pkg/secrets"
+	"github.com/redskal/dragonvomit/pkg/store"
+)
+
+// docFileName pulls the file name out of a document URL
+func docFileName(docUrl string) string {
+	urlParts := strings.Split(docUrl, "/")
+	fileName, _ := url.QueryUnescape(urlParts[len(urlParts)-1])
+	return fileName
+}
+
+// resultFindings flattens a single document's results into findings,
+// using the same types as flattenFindings
+func resultFindings(result analysisResult) []Finding {
+	fileName := docFileName(result.url)
+	var findings []Finding
+	add := func(findingType, subtype string, values ...string) {
+		for _, value := range values {
+			findings = append(findings, Finding{
+				Type:     findingType,
+				Subtype:  subtype,
+				Value:    value,
+				FileName: fileName,
+				FileUrl:  result.url,
+			})
+		}
+	}
+
+	add("external_links", "", result.ExternalLinks...)
+	add("image_links", "", result.ImageLinks...)
+	add("file_paths", "", result.FilePaths...)
+	add("printers", "", result.Printers...)
+	add("hostnames", "", result.Hostnames...)
+	add("emails", "", result.Emails...)
+	add("names", "", result.Names...)
+	add("usernames", "", result.Usernames...)
+	add("grepped_values", "", result.GrepKeywords...)
+	add("hidden_sheets", "", result.HiddenSheets...)
+	add("last_saved_paths", "", result.LastSavedPath...)
+	add("software", "", result.Software...)
+	add("fonts", "", result.Fonts...)
+	for _, s := range result.Secrets {
+		add("secrets", s.Type, s.Value)
+	}
+	for _, n := range result.NetworkIntel {
+		add("network_intel", n.Type, n.Value)
+	}
+	if result.EmbeddedDocs {
+		add("embedded_docs", "", "")
+	}
+	if result.EmbeddedMedia {
+		add("embedded_media", "", "")
+	}
+
+	return findings
+}
+
+// saveResult records a document and its findings in the project database
+func saveResult(db *store.Store, runId int64, result analysisResult) error {
+	doc := store.Document{
+		URL:            result.url,
+		FileName:       docFileName(result.url),
+		FileType:       result.fileType,
+		Status:         result.status,
+		Error:          result.err,
+		HTTPStatus:     result.httpStatus,
+		ContentType:    result.contentType,
+		Size:           result.size,
+		SHA256:         result.sha256,
+		Creator:        result.creator,
+		LastModifiedBy: result.lastModifiedBy,
+		Created:        result.created,
+		Modified:       result.modified,
+		Printed:        result.printed,
+		FetchedAt:      result.fetched,
+		DuplicateOf:    result.duplicateOf,
+		AppVersion:     result.appVersion,
+	}
+
+	var findings []store.Finding
+	for _, f := range resultFindings(result) {
+		findings = append(findings, store.Finding{Type: f.Type, Subtype: f.Subtype, Value: f.Value})
+	}
+
+	return db.SaveDocument(runId, doc, findings)
+}
+
+// loadResult rebuilds the analysis of a document from an earlier run so
+// it still counts towards this run's results
+func loadResult(db *store.Store, dork dorkResult) (analysisResult, error) {
+	doc, findings, err := db.Document(dork.url)
+	if err != nil {
+		return analysisResult{}, err
+	}
+
+	result := analysisResult{
+		url:            doc.URL,
+		fileType:       doc.FileType,
+		searchEngine:   dork.searchEngine,
+		crawled:        dork.crawled,
+		status:         doc.Status,
+		err:            doc.Error,
+		httpStatus:     doc.HTTPStatus,
+		contentType:    doc.ContentType,
+		size:           doc.Size,
+		sha256:         doc.SHA256,
+		fetched:        doc.FetchedAt,
+		creator:        doc.Creator,
+		lastModifiedBy: doc.LastModifiedBy,
+		created:        doc.Created,
+		modified:       doc.Modified,
+		printed:        doc.Printed,
+		duplicateOf:    doc.DuplicateOf,
+		appVersion:     doc.AppVersion,
+	}
+
+	var found []Finding
+	for _, f := range findings {
+		found = append(found, Finding{Type: f.Type, Subtype: f.Subtype, Value: f.Value})
+	}
+	applyFindings(&result, found)
+
+	return result, nil
+}
+
+// applyFindings is the reverse of resultFindings, putting each finding
+// back where it came from in result
+func applyFindings(result *analysisResult, findings []Finding) {
+	for _, f := range findings {
+		switch f.Type {
+		case "external_links":
+			result.ExternalLinks = append(result.ExternalLinks, f.Value)
+		case "image_links":
+			result.ImageLinks = append(result.ImageLinks, f.Value)
+		case "file_paths":
+			result.FilePaths = append(result.FilePaths, f.Value)
+		case "printers":
+			result.Printers = append(result.Printers, f.Value)
+		case "hostnames":
+			result.Hostnames = append(result.Hostnames, f.Value)
+		case "emails":
+			result.Emails = append(result.Emails, f.Value)
+		case "names":
+			result.Names = append(result.Names, f.Value)
+		case "usernames":
+			result.Usernames = append(result.Usernames, f.Value)
+		case "grepped_values":
+			result.GrepKeywords = append(result.GrepKeywords, f.Value)
+		case "hidden_sheets":
+			result.HiddenSheets = append(result.HiddenSheets, f.Value)
+		case "last_saved_paths":
+			result.LastSavedPath = append(result.LastSavedPath, f.Value)
+		case "software":
+			result.Software = append(result.Software, f.Value)
+		case "fonts":
+			result.Fonts = append(result.Fonts, f.Value)
+		case "secrets":
+			result.Secrets = append(result.Secrets, secrets.Finding{Type: f.Subtype, Value: f.Value})
+		case "network_intel":
+			result.NetworkIntel = append(result.NetworkIntel, netintel.Finding{Type: f.Subtype, Value: f.Value})
+		case "embedded_docs":
+			result.EmbeddedDocs = true
+		case "embedded_media":
+			result.EmbeddedMedia = true
+		}
+	}
+}
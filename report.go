@@ -0,0 +1,250 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	textTemplate "text/template"
+	"time"
+
+	"github.com/redskal/dragonvomit/pkg/secrets"
+)
+
+//go:embed templates/report.html
+var reportTemplate string
+
+//go:embed templates/report.md
+var markdownTemplate string
+
+// characters that mean something inside a Markdown table cell
+var markdownEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"|", "\\|",
+	"`", "\\`",
+	"*", "\\*",
+	"_", "\\_",
+	"<", "&lt;",
+	">", "&gt;",
+	"\r\n", " ",
+	"\n", " ",
+	"\r", " ",
+)
+
+// categoryTitles are the human names for each finding category
+var categoryTitles = map[string]string{
+	"documents":        "Documents",
+	"external_links":   "External Links",
+	"image_links":      "Image Links",
+	"file_paths":       "File Paths",
+	"printers":         "Printers",
+	"hostnames":        "Hostnames",
+	"emails":           "Email Addresses",
+	"names":            "Names",
+	"usernames":        "Usernames",
+	"grepped_values":   "Grepped Values",
+	"hidden_sheets":    "Hidden Sheets",
+	"last_saved_paths": "Last Saved Paths",
+	"software":         "Software",
+	"fonts":            "Fonts",
+	"secrets":          "Secrets",
+	"network_intel":    "Network Intel",
+	"embedded_docs":    "Containing Embedded Docs",
+	"embedded_media":   "Containing Embedded Media",
+}
+
+type reportStat struct {
+	Label string
+	Value string
+}
+
+type reportCategory struct {
+	ID           string
+	Title        string
+	SubtypeTitle string
+	ValueTitle   string
+	Findings     []Finding
+}
+
+type reportDocument struct {
+	ID       string
+	Document Document
+	OS       string
+	Findings []Finding
+}
+
+type reportData struct {
+	Domain     string
+	Generated  string
+	Stats      []reportStat
+	EOLCount   int
+	Results    FinalResult
+	Categories []reportCategory
+	Documents  []reportDocument
+}
+
+// buildReport shapes the results for the report templates. Secrets are
+// redacted the same way they are on the console.
+func buildReport(results FinalResult, domain, redactMode string) reportData {
+	data := reportData{
+		Domain:    domain,
+		Generated: time.Now().Format("2006-01-02 15:04 MST"),
+		Results:   results,
+	}
+
+	findings := flattenFindings(results)
+	for i, f := range findings {
+		if f.Type == "secrets" {
+			findings[i].Value = secrets.Finding{Type: f.Subtype, Value: f.Value}.Redact(redactMode)
+		}
+	}
+
+	// headline numbers
+	for _, s := range results.SoftwareInventory {
+		if s.EOL {
+			data.EOLCount++
+		}
+	}
+	data.Stats = []reportStat{
+		{"Documents", fmt.Sprint(len(results.Documents))},
+		{"People", fmt.Sprint(len(results.People))},
+		{"Email Addresses", fmt.Sprint(len(results.Emails))},
+		{"Usernames", fmt.Sprint(len(results.Usernames))},
+		{"Hostnames", fmt.Sprint(len(results.Hostnames))},
+		{"Secrets", fmt.Sprint(len(results.Secrets))},
+		{"End-of-life Software", fmt.Sprint(data.EOLCount)},
+	}
+	if len(results.OSDistribution) > 0 {
+		data.Stats = append(data.Stats, reportStat{"Most Common OS", results.OSDistribution[0].OS})
+	}
+	if len(results.Domains) > 0 {
+		data.Stats = append(data.Stats, reportStat{"Probable AD Domain", results.Domains[0].NetBIOS})
+	}
+	if results.UserFormat != nil && results.UserFormat.UsernameFormat != "" {
+		data.Stats = append(data.Stats, reportStat{"Username Format", results.UserFormat.UsernameFormat})
+	}
+
+	// a table per category. documents get their own section below.
+	for _, category := range findingCategories {
+		if category == "documents" {
+			continue
+		}
+		c := reportCategory{
+			ID:           strings.ReplaceAll(category, "_", "-"),
+			Title:        categoryTitles[category],
+			SubtypeTitle: columnTitle(csvSubtypeColumn[category]),
+			ValueTitle:   columnTitle(csvValueColumn[category]),
+		}
+		for _, f := range findings {
+			if f.Type == category {
+				c.Findings = append(c.Findings, f)
+			}
+		}
+		if len(c.Findings) > 0 {
+			data.Categories = append(data.Categories, c)
+		}
+	}
+
+	// everything found in each document
+	osByUrl := make(map[string]string)
+	for _, p := range results.Platforms {
+		osByUrl[p.FileUrl] = fmt.Sprintf("%s (%s)", p.OS, p.Confidence)
+	}
+	for i, d := range results.Documents {
+		doc := reportDocument{
+			ID:       fmt.Sprintf("doc-%d", i),
+			Document: d,
+			OS:       osByUrl[d.FileUrl],
+		}
+		for _, f := range findings {
+			if f.Type != "documents" && f.FileUrl == d.FileUrl {
+				doc.Findings = append(doc.Findings, f)
+			}
+		}
+		data.Documents = append(data.Documents, doc)
+	}
+
+	return data
+}
+
+// columnTitle turns a CSV column name like "email_address" into "Email Address"
+func columnTitle(column string) string {
+	words := strings.Fields(strings.ReplaceAll(column, "_", " "))
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// writeHtmlReport renders a single-file HTML report to fileName
+func writeHtmlReport(results FinalResult, domain, redactMode, fileName string) error {
+	funcs := template.FuncMap{
+		"title":     func(category string) string { return categoryTitles[category] },
+		"join":      strings.Join,
+		"histogram": renderHistogram,
+		// only link things that are actually web addresses
+		"isLink": func(s string) bool {
+			lower := strings.ToLower(s)
+			return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+		},
+	}
+	tmpl, err := template.New("report").Funcs(funcs).Parse(reportTemplate)
+	if err != nil {
+		return fmt.Errorf("unable to parse report template: %w", err)
+	}
+
+	f, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %w", fileName, err)
+	}
+	defer f.Close()
+
+	if err := tmpl.Execute(f, buildReport(results, domain, redactMode)); err != nil {
+		return fmt.Errorf("error writing HTML report: %w", err)
+	}
+
+	return nil
+}
+
+// writeMarkdownReport writes an executive summary, findings tables and
+// an appendix of document sources to fileName as Markdown. Findings
+// refer to documents by their appendix number to keep tables narrow.
+func writeMarkdownReport(results FinalResult, domain, redactMode, fileName string) error {
+	data := buildReport(results, domain, redactMode)
+
+	refs := make(map[string]string)
+	for i, d := range data.Documents {
+		if _, ok := refs[d.Document.FileUrl]; !ok {
+			refs[d.Document.FileUrl] = fmt.Sprintf("D%d", i+1)
+		}
+	}
+
+	funcs := textTemplate.FuncMap{
+		"md":   markdownEscaper.Replace,
+		"join": strings.Join,
+		"inc":  func(i int) int { return i + 1 },
+		"docref": func(fileUrl string) string {
+			if ref, ok := refs[fileUrl]; ok {
+				return ref
+			}
+			return "?"
+		},
+	}
+	tmpl, err := textTemplate.New("markdown").Funcs(funcs).Parse(markdownTemplate)
+	if err != nil {
+		return fmt.Errorf("unable to parse Markdown template: %w", err)
+	}
+
+	f, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %w", fileName, err)
+	}
+	defer f.Close()
+
+	if err := tmpl.Execute(f, data); err != nil {
+		return fmt.Errorf("error writing Markdown report: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/redskal/dragonvomit/pkg/bing"
+	"google.golang.org/api/googleapi"
+)
+
+// the first retry waits about this long, doubling each time after
+const retryBaseDelay = time.Second
+
+// retryPolicy is how hard we try before giving up on something
+type retryPolicy struct {
+	retries    int           // extra attempts after the first
+	maxBackoff time.Duration // longest we'll wait between attempts
+}
+
+// statusError is an HTTP error response to a document request
+type statusError struct {
+	code       int
+	status     string
+	retryAfter string
+}
+
+func (e *statusError) Error() string {
+	return e.status
+}
+
+// do runs fn until it works, fails in a way that won't get better, or
+// we run out of retries. what describes fn for progress messages.
+func (p retryPolicy) do(ctx context.Context, what string, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		retryAfter, ok := retryable(err)
+		if !ok || attempt >= p.retries || ctx.Err() != nil {
+			return err
+		}
+
+		// the server knows better than we do how long to wait, but
+		// we won't wait forever for it
+		delay := p.backoff(attempt)
+		if retryAfter > delay {
+			delay = min(retryAfter, p.maxBackoff)
+		}
+		if !silent {
+			fmt.Printf("[i] Retrying %s in %s: %v\n", what, delay.Round(100*time.Millisecond), err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// backoff doubles the delay each attempt, up to the cap. Half of it is
+// random, so a burst of failures doesn't all retry at once.
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := p.maxBackoff
+	if attempt < 32 && retryBaseDelay<<attempt < p.maxBackoff {
+		delay = retryBaseDelay << attempt
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retryable works out whether an error is worth another go, and how
+// long the server asked us to wait, if it did
+func retryable(err error) (time.Duration, bool) {
+	if errors.Is(err, context.Canceled) {
+		return 0, false
+	}
+
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return parseRetryAfter(statusErr.retryAfter), retryableStatus(statusErr.code)
+	}
+	var bingErr *bing.StatusError
+	if errors.As(err, &bingErr) {
+		return parseRetryAfter(bingErr.RetryAfter), retryableStatus(bingErr.StatusCode)
+	}
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) {
+		return parseRetryAfter(googleErr.Header.Get("Retry-After")), retryableStatus(googleErr.Code)
+	}
+
+	// timeouts, dropped connections and DNS hiccups
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return 0, true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return 0, dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// retryableStatus is true for responses that mean "not right now"
+// rather than "no"
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter handles both forms of Retry-After: a number of
+// seconds, or a date
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+// failure is something we gave up on, and why
+type failure struct {
+	item   string
+	reason string
+}
+
+// printFailures tallies up the dorks and documents that failed for good.
+// Documents cut short by an interrupt aren't counted, as resuming will
+// have another go at them.
+func printFailures(dorkFailures []failure, results []analysisResult) {
+	failures := dorkFailures
+	for _, r := range results {
+		if r.status == statusAnalysed || r.status == statusDuplicate {
+			continue
+		}
+		if strings.Contains(r.err, context.Canceled.Error()) {
+			continue
+		}
+		failures = append(failures, failure{item: r.url, reason: fmt.Sprintf("%s: %s", r.status, r.err)})
+	}
+	if len(failures) == 0 {
+		return
+	}
+
+	fmt.Printf("[!] %d item(s) failed:\n", len(failures))
+	for _, f := range failures {
+		fmt.Printf("    %s - %s\n", f.item, f.reason)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// how often to checkpoint while documents are coming in
+const checkpointInterval = 5 * time.Second
+
+// anything that shouldn't go in a scan ID
+var scanIdRegex = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// scanState is everything needed to pick a scan back up where it left
+// off. It's saved as JSON in the scans directory.
+type scanState struct {
+	ID         string       `json:"id"`
+	Domain     string       `json:"domain"`
+	Extensions string       `json:"extensions"`
+	Started    time.Time    `json:"started"`
+	Updated    time.Time    `json:"updated"`
+	Dorks      []string     `json:"completed_dorks,omitempty"`
+	Urls       []scanUrl    `json:"urls,omitempty"`
+	Results    []scanResult `json:"results,omitempty"`
+}
+
+type scanUrl struct {
+	Url          string    `json:"url"`
+	SearchEngine string    `json:"search_engine"`
+	Crawled      time.Time `json:"crawled,omitempty"`
+}
+
+type scanResult struct {
+	Url            string    `json:"url"`
+	FileType       string    `json:"file_type,omitempty"`
+	SearchEngine   string    `json:"search_engine,omitempty"`
+	Crawled        time.Time `json:"crawled,omitempty"`
+	Status         string    `json:"status"`
+	Error          string    `json:"error,omitempty"`
+	HTTPStatus     int       `json:"http_status,omitempty"`
+	ContentType    string    `json:"content_type,omitempty"`
+	Size           int64     `json:"size,omitempty"`
+	SHA256         string    `json:"sha256,omitempty"`
+	Fetched        time.Time `json:"fetched,omitempty"`
+	DuplicateOf    string    `json:"duplicate_of,omitempty"`
+	Creator        string    `json:"creator,omitempty"`
+	LastModifiedBy string    `json:"last_modified_by,omitempty"`
+	Created        time.Time `json:"created,omitempty"`
+	Modified       time.Time `json:"modified,omitempty"`
+	Printed        time.Time `json:"printed,omitempty"`
+	AppVersion     string    `json:"app_version,omitempty"`
+	Findings       []Finding `json:"findings,omitempty"`
+}
+
+// scanCheckpoint keeps a scan's state on disk as it progresses
+type scanCheckpoint struct {
+	mu       sync.Mutex
+	fileName string
+	state    scanState
+	lastSave time.Time
+	results  map[string]int // URL to index in state.Results
+}
+
+// newScan starts a fresh scan, with an ID made from the domain and time
+func newScan(dir, domain, extensions string) *scanCheckpoint {
+	now := time.Now()
+	id := fmt.Sprintf("%s-%s", scanIdRegex.ReplaceAllString(domain, "_"), now.Format("20060102-150405"))
+	return &scanCheckpoint{
+		fileName: filepath.Join(dir, id+".json"),
+		state: scanState{
+			ID:         id,
+			Domain:     domain,
+			Extensions: extensions,
+			Started:    now,
+		},
+	}
+}
+
+// loadScan picks up the scan with the given ID from dir
+func loadScan(dir, id string) (*scanCheckpoint, error) {
+	fileName := filepath.Join(dir, scanIdRegex.ReplaceAllString(id, "_")+".json")
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find scan %s: %w", id, err)
+	}
+
+	c := &scanCheckpoint{fileName: fileName}
+	if err := json.Unmarshal(content, &c.state); err != nil {
+		return nil, fmt.Errorf("unable to read scan %s: %w", id, err)
+	}
+	return c, nil
+}
+
+// dorkDone reports whether a dork finished in an earlier run
+func (c *scanCheckpoint) dorkDone(engine, extension string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Contains(c.state.Dorks, engine+":"+extension)
+}
+
+// finishDork marks a dork as done, so it's not run again on resume
+func (c *scanCheckpoint) finishDork(engine, extension string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state.Dorks = append(c.state.Dorks, engine+":"+extension)
+	c.saveLocked()
+}
+
+// addUrl records a newly dorked document URL
+func (c *scanCheckpoint) addUrl(r dorkResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state.Urls = append(c.state.Urls, scanUrl{Url: r.url, SearchEngine: r.searchEngine, Crawled: r.crawled})
+}
+
+// urls returns every document URL dorked so far
+func (c *scanCheckpoint) urls() []dorkResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var urls []dorkResult
+	for _, u := range c.state.Urls {
+		urls = append(urls, dorkResult{searchEngine: u.SearchEngine, url: u.Url, crawled: u.Crawled})
+	}
+	return urls
+}
+
+// addResult records what happened to a document. We checkpoint every
+// few seconds rather than every document, as the file gets big.
+func (c *scanCheckpoint) addResult(result analysisResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r := scanResult{
+		Url:            result.url,
+		FileType:       result.fileType,
+		SearchEngine:   result.searchEngine,
+		Crawled:        result.crawled,
+		Status:         result.status,
+		Error:          result.err,
+		HTTPStatus:     result.httpStatus,
+		ContentType:    result.contentType,
+		Size:           result.size,
+		SHA256:         result.sha256,
+		Fetched:        result.fetched,
+		DuplicateOf:    result.duplicateOf,
+		Creator:        result.creator,
+		LastModifiedBy: result.lastModifiedBy,
+		Created:        result.created,
+		Modified:       result.modified,
+		Printed:        result.printed,
+		AppVersion:     result.appVersion,
+	}
+	for _, f := range resultFindings(result) {
+		r.Findings = append(r.Findings, Finding{Type: f.Type, Subtype: f.Subtype, Value: f.Value})
+	}
+
+	// big targets have a lot of results, so don't search for this one
+	if c.results == nil {
+		c.results = make(map[string]int, len(c.state.Results))
+		for i, s := range c.state.Results {
+			c.results[s.Url] = i
+		}
+	}
+	if i, ok := c.results[r.Url]; ok {
+		c.state.Results[i] = r
+	} else {
+		c.results[r.Url] = len(c.state.Results)
+		c.state.Results = append(c.state.Results, r)
+	}
+
+	if time.Since(c.lastSave) > checkpointInterval {
+		c.saveLocked()
+	}
+}
+
+// settled reports whether fetching a document again would make any
+// difference. Network failures and "try later" responses get another
+// go; anything else won't change.
+func settled(status string, httpStatus int) bool {
+	switch status {
+	case statusAnalysed, statusDuplicate, statusRejected, statusParseFailed:
+		return true
+	case statusHttpError:
+		return !retryableStatus(httpStatus)
+	}
+	return false
+}
+
+// completed returns the documents that don't need fetching again
+func (c *scanCheckpoint) completed() []analysisResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var results []analysisResult
+	for _, r := range c.state.Results {
+		if !settled(r.Status, r.HTTPStatus) {
+			continue
+		}
+		result := analysisResult{
+			url:            r.Url,
+			fileType:       r.FileType,
+			searchEngine:   r.SearchEngine,
+			crawled:        r.Crawled,
+			status:         r.Status,
+			err:            r.Error,
+			httpStatus:     r.HTTPStatus,
+			contentType:    r.ContentType,
+			size:           r.Size,
+			sha256:         r.SHA256,
+			fetched:        r.Fetched,
+			duplicateOf:    r.DuplicateOf,
+			creator:        r.Creator,
+			lastModifiedBy: r.LastModifiedBy,
+			created:        r.Created,
+			modified:       r.Modified,
+			printed:        r.Printed,
+			appVersion:     r.AppVersion,
+		}
+		applyFindings(&result, r.Findings)
+		results = append(results, result)
+	}
+	return results
+}
+
+// save writes the checkpoint to disk
+func (c *scanCheckpoint) save() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.saveLocked()
+}
+
+// saveLocked writes to a temporary file first, so an interruption
+// mid-write can't leave us with half a checkpoint. It's full of
+// findings, secrets included, so only we get to read it.
+func (c *scanCheckpoint) saveLocked() {
+	c.lastSave = time.Now()
+	c.state.Updated = c.lastSave
+
+	content, err := json.Marshal(c.state)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "[!] Unable to checkpoint scan:", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.fileName), 0700); err != nil {
+		fmt.Fprintln(os.Stderr, "[!] Unable to checkpoint scan:", err)
+		return
+	}
+	tmpFile := c.fileName + ".tmp"
+	if err := os.WriteFile(tmpFile, content, 0600); err != nil {
+		fmt.Fprintln(os.Stderr, "[!] Unable to checkpoint scan:", err)
+		return
+	}
+	if err := os.Rename(tmpFile, c.fileName); err != nil {
+		fmt.Fprintln(os.Stderr, "[!] Unable to checkpoint scan:", err)
+	}
+}
+
+// remove deletes the checkpoint once the scan's finished, as there's
+// nothing left to resume
+func (c *scanCheckpoint) remove() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Remove(c.fileName); err != nil && !os.IsNotExist(err) {
+		fmt.Fprintln(os.Stderr, "[!] Unable to remove scan checkpoint:", err)
+	}
+}
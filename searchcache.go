@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// searchCache keeps search engine results on disk so repeat runs don't
+// cost money or quota
+type searchCache struct {
+	dir     string
+	ttl     time.Duration
+	refresh bool // ignore what's cached, but still update it
+
+	// for the end of run report
+	hits atomic.Int64
+	paid atomic.Int64
+}
+
+// cachedSearch is one page of results for a query
+type cachedSearch struct {
+	Engine  string      `json:"engine"`
+	Query   string      `json:"query"`
+	Page    int         `json:"page"`
+	Fetched time.Time   `json:"fetched"`
+	Results []cachedUrl `json:"results"`
+}
+
+type cachedUrl struct {
+	Url     string    `json:"url"`
+	Crawled time.Time `json:"crawled,omitempty"`
+}
+
+// fileName is where a query's results live. The query can be anything,
+// so hash it rather than trying to make it safe.
+func (c *searchCache) fileName(engine, query string, page int) string {
+	key := fmt.Sprintf("%s\x00%s\x00%d", engine, query, page)
+	return filepath.Join(c.dir, fmt.Sprintf("%x.json", sha256.Sum256([]byte(key))))
+}
+
+// get returns cached results if we have them and they've not expired
+func (c *searchCache) get(engine, query string, page int) ([]dorkResult, bool) {
+	if c == nil || c.ttl <= 0 || c.refresh {
+		return nil, false
+	}
+
+	content, err := os.ReadFile(c.fileName(engine, query, page))
+	if err != nil {
+		return nil, false
+	}
+	var cached cachedSearch
+	if err := json.Unmarshal(content, &cached); err != nil {
+		return nil, false
+	}
+	if time.Since(cached.Fetched) > c.ttl {
+		return nil, false
+	}
+
+	var results []dorkResult
+	for _, r := range cached.Results {
+		results = append(results, dorkResult{searchEngine: engine, url: r.Url, crawled: r.Crawled})
+	}
+	c.hits.Add(1)
+	return results, true
+}
+
+// put caches a page of results we've just paid for. A query that came
+// back empty is still worth caching - we paid for that answer too.
+func (c *searchCache) put(engine, query string, page int, results []dorkResult) {
+	if c == nil {
+		return
+	}
+	c.paid.Add(1)
+	if c.ttl <= 0 {
+		return
+	}
+
+	cached := cachedSearch{Engine: engine, Query: query, Page: page, Fetched: time.Now()}
+	for _, r := range results {
+		cached.Results = append(cached.Results, cachedUrl{Url: r.url, Crawled: r.crawled})
+	}
+	content, err := json.Marshal(cached)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.dir, os.ModePerm); err != nil {
+		fmt.Fprintln(os.Stderr, "[!] Unable to cache search results:", err)
+		return
+	}
+	if err := os.WriteFile(c.fileName(engine, query, page), content, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "[!] Unable to cache search results:", err)
+	}
+}
@@ -1,46 +1,119 @@
 package main
 
+import (
+	"strings"
+	"time"
+
+	"github.com/redskal/dragonvomit/pkg/netintel"
+	"github.com/redskal/dragonvomit/pkg/secrets"
+)
+
 type empty struct{}
 
+// headerFlags collects every -header given on the command line
+type headerFlags []string
+
+func (h *headerFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlags) Set(value string) error {
+	*h = append(*h, value)
+	return nil
+}
+
 type dorkResult struct {
 	searchEngine string
 	url          string
+	crawled      time.Time
 }
 
+// how fetching and analysing a document went
+const (
+	statusAnalysed    = "analysed"
+	statusFetchFailed = "fetch-failed"
+	statusHttpError   = "http-error"
+	statusReadFailed  = "read-failed"
+	statusParseFailed = "parse-failed"
+	statusRejected    = "rejected"  // over -maxsize
+	statusDuplicate   = "duplicate" // same content as another URL
+)
+
 type analysisResult struct {
-	url           string
-	fileType      string
-	ExternalLinks []string
-	ImageLinks    []string
-	FilePaths     []string
-	Printers      []string
-	Hostnames     []string
-	Emails        []string
-	Names         []string
-	Usernames     []string
-	GrepKeywords  []string
-	HiddenSheets  []string
-	LastSavedPath []string
-	Software      []string
-	EmbeddedDocs  bool
-	EmbeddedMedia bool
+	url            string
+	fileType       string
+	searchEngine   string
+	crawled        time.Time
+	status         string
+	err            string
+	httpStatus     int
+	contentType    string
+	size           int64
+	sha256         string
+	fetched        time.Time
+	duplicateOf    string
+	creator        string
+	lastModifiedBy string
+	created        time.Time
+	modified       time.Time
+	printed        time.Time
+	appVersion     string // Office AppVersion, eg. 16.0000
+	ExternalLinks  []string
+	ImageLinks     []string
+	FilePaths      []string
+	Printers       []string
+	Hostnames      []string
+	Emails         []string
+	Names          []string
+	Usernames      []string
+	GrepKeywords   []string
+	HiddenSheets   []string
+	LastSavedPath  []string
+	Software       []string
+	Fonts          []string
+	Secrets        []secrets.Finding
+	NetworkIntel   []netintel.Finding
+	EmbeddedDocs   bool
+	EmbeddedMedia  bool
 }
 
 type FinalResult struct {
-	ExternalLinks  []ExternalLink  `json:"external_links,omitempty"`
-	ImageLinks     []ImageLink     `json:"image_links,omitempty"`
-	FilePaths      []FilePath      `json:"file_paths,omitempty"`
-	Printers       []Printer       `json:"printers,omitempty"`
-	Hostnames      []Hostname      `json:"hostnames,omitempty"`
-	Emails         []Email         `json:"emails,omitempty"`
-	Names          []Name          `json:"names,omitempty"`
-	Usernames      []Username      `json:"usernames,omitempty"`
-	GreppedValues  []Grepped       `json:"grepped_values,omitempty"`
-	HiddenSheets   []HiddenSheet   `json:"hidden_sheets,omitempty"`
-	LastSavedPaths []LastSavedPath `json:"last_saved_paths,omitempty"`
-	Softwares      []Software      `json:"software,omitempty"`
-	EmbeddedDocs   []EmbeddedDoc   `json:"embedded_docs,omitempty"`
-	EmbeddedMedias []EmbeddedMedia `json:"embedded_media,omitempty"`
+	Documents         []Document        `json:"documents,omitempty"`
+	ExternalLinks     []ExternalLink    `json:"external_links,omitempty"`
+	ImageLinks        []ImageLink       `json:"image_links,omitempty"`
+	FilePaths         []FilePath        `json:"file_paths,omitempty"`
+	Printers          []Printer         `json:"printers,omitempty"`
+	Hostnames         []Hostname        `json:"hostnames,omitempty"`
+	Emails            []Email           `json:"emails,omitempty"`
+	Names             []Name            `json:"names,omitempty"`
+	Usernames         []Username        `json:"usernames,omitempty"`
+	GreppedValues     []Grepped         `json:"grepped_values,omitempty"`
+	HiddenSheets      []HiddenSheet     `json:"hidden_sheets,omitempty"`
+	LastSavedPaths    []LastSavedPath   `json:"last_saved_paths,omitempty"`
+	Softwares         []Software        `json:"software,omitempty"`
+	Secrets           []Secret          `json:"secrets,omitempty"`
+	NetworkIntel      []NetworkIntel    `json:"network_intel,omitempty"`
+	Domains           []DomainCandidate `json:"domains,omitempty"`
+	UserFormat        *UserFormat       `json:"user_format,omitempty"`
+	People            []Person          `json:"people,omitempty"`
+	SoftwareInventory []SoftwareItem    `json:"software_inventory,omitempty"`
+	Fonts             []Font            `json:"fonts,omitempty"`
+	Platforms         []Platform        `json:"platforms,omitempty"`
+	OSDistribution    []OSCount         `json:"os_distribution,omitempty"`
+	Timeline          []TimelineEvent   `json:"timeline,omitempty"`
+	WorkPatterns      *WorkPatterns     `json:"work_patterns,omitempty"`
+	EmbeddedDocs      []EmbeddedDoc     `json:"embedded_docs,omitempty"`
+	EmbeddedMedias    []EmbeddedMedia   `json:"embedded_media,omitempty"`
+}
+
+type Document struct {
+	FileName     string   `json:"file_name,omitempty"`
+	FileUrl      string   `json:"file_url,omitempty"`
+	FileType     string   `json:"file_type,omitempty"`
+	SearchEngine string   `json:"search_engine,omitempty"`
+	SHA256       string   `json:"sha256,omitempty"`
+	AppVersion   string   `json:"app_version,omitempty"`
+	AlsoAt       []string `json:"also_at,omitempty"` // identical copies at other URLs
 }
 
 type ExternalLink struct {
@@ -115,6 +188,105 @@ type Software struct {
 	FileUrl  string `json:"file_url,omitempty"`
 }
 
+type Secret struct {
+	Type     string `json:"type,omitempty"`
+	Value    string `json:"value,omitempty"`
+	FileName string `json:"file_name,omitempty"`
+	FileUrl  string `json:"file_url,omitempty"`
+}
+
+type NetworkIntel struct {
+	Type     string `json:"type,omitempty"`
+	Value    string `json:"value,omitempty"`
+	FileName string `json:"file_name,omitempty"`
+	FileUrl  string `json:"file_url,omitempty"`
+}
+
+type DomainCandidate struct {
+	NetBIOS    string   `json:"netbios,omitempty"`
+	DNSNames   []string `json:"dns_names,omitempty"`
+	Score      float64  `json:"score"`
+	Confidence string   `json:"confidence,omitempty"`
+	Evidence   []string `json:"evidence,omitempty"`
+}
+
+type UserFormat struct {
+	UsernameFormat  string   `json:"username_format,omitempty"`
+	UsernameMatches int      `json:"username_matches"`
+	EmailFormat     string   `json:"email_format,omitempty"`
+	EmailMatches    int      `json:"email_matches"`
+	EmailDomain     string   `json:"email_domain,omitempty"`
+	Usernames       []string `json:"usernames,omitempty"`
+	Emails          []string `json:"emails,omitempty"`
+}
+
+type Person struct {
+	Name      string   `json:"name,omitempty"`
+	Usernames []string `json:"usernames,omitempty"`
+	Emails    []string `json:"emails,omitempty"`
+	Documents []string `json:"documents,omitempty"`
+	Evidence  []string `json:"evidence,omitempty"`
+}
+
+type SoftwareItem struct {
+	Vendor  string   `json:"vendor,omitempty"`
+	Product string   `json:"product,omitempty"`
+	Version string   `json:"version,omitempty"`
+	OS      string   `json:"os,omitempty"`
+	Count   int      `json:"count"`
+	EOL     bool     `json:"eol"`
+	EOLDate string   `json:"eol_date,omitempty"`
+	Raw     []string `json:"raw,omitempty"`
+}
+
+type Font struct {
+	Font     string `json:"font,omitempty"`
+	FileName string `json:"file_name,omitempty"`
+	FileUrl  string `json:"file_url,omitempty"`
+}
+
+type Platform struct {
+	OS         string   `json:"os,omitempty"`
+	Confidence string   `json:"confidence,omitempty"`
+	Evidence   []string `json:"evidence,omitempty"`
+	FileName   string   `json:"file_name,omitempty"`
+	FileUrl    string   `json:"file_url,omitempty"`
+}
+
+type OSCount struct {
+	OS    string `json:"os"`
+	Count int    `json:"count"`
+}
+
+type TimelineEvent struct {
+	Time     time.Time `json:"time"`
+	Event    string    `json:"event,omitempty"`
+	Person   string    `json:"person,omitempty"`
+	Source   string    `json:"source,omitempty"`
+	FileName string    `json:"file_name,omitempty"`
+	FileUrl  string    `json:"file_url,omitempty"`
+}
+
+type WorkPatterns struct {
+	Offsets []OffsetCount `json:"utc_offsets,omitempty"`
+	Authors []AuthorHours `json:"authors,omitempty"`
+}
+
+type OffsetCount struct {
+	Offset string `json:"offset"`
+	Count  int    `json:"count"`
+}
+
+type AuthorHours struct {
+	Person    string   `json:"person,omitempty"`
+	Offsets   []string `json:"utc_offsets,omitempty"`
+	Histogram [24]int  `json:"histogram"` // local hour of day
+	Start     int      `json:"typical_start"`
+	End       int      `json:"typical_end"`
+	Samples   int      `json:"samples"`
+	Assumed   int      `json:"assumed_offset"` // samples without their own offset
+}
+
 type EmbeddedDoc struct {
 	FileName string `json:"file_name,omitempty"`
 	FileUrl  string `json:"file_url,omitempty"`
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// fetchThrottle spaces out document requests, overall and per host, and
+// caps how many requests each host has in flight at once. It lets us go
+// low-and-slow however many threads are running.
+type fetchThrottle struct {
+	interval     time.Duration // between any two requests
+	hostInterval time.Duration // between two requests to the same host
+	jitter       time.Duration // random extra delay, up to this much
+	hostConns    int           // requests in flight per host, 0 for no cap
+
+	mu    sync.Mutex
+	next  time.Time
+	hosts map[string]*hostThrottle
+}
+
+type hostThrottle struct {
+	next  time.Time
+	conns chan empty
+}
+
+// newFetchThrottle turns requests-per-second rates into intervals. A
+// rate of 0 means no limit.
+func newFetchThrottle(rate, hostRate float64, jitter time.Duration, hostConns int) *fetchThrottle {
+	t := &fetchThrottle{
+		jitter:    jitter,
+		hostConns: hostConns,
+		hosts:     make(map[string]*hostThrottle),
+	}
+	if rate > 0 {
+		t.interval = time.Duration(float64(time.Second) / rate)
+	}
+	if hostRate > 0 {
+		t.hostInterval = time.Duration(float64(time.Second) / hostRate)
+	}
+	return t
+}
+
+// host gets the state for a host, setting it up the first time
+func (t *fetchThrottle) host(name string) *hostThrottle {
+	h, ok := t.hosts[name]
+	if !ok {
+		h = &hostThrottle{}
+		if t.hostConns > 0 {
+			h.conns = make(chan empty, t.hostConns)
+		}
+		t.hosts[name] = h
+	}
+	return h
+}
+
+// wait blocks until we're allowed to request rawUrl. The returned func
+// must be called once the request is finished with, to let the next
+// one to that host go.
+func (t *fetchThrottle) wait(ctx context.Context, rawUrl string) (func(), error) {
+	if t == nil {
+		return func() {}, nil
+	}
+
+	var hostName string
+	if u, err := url.Parse(rawUrl); err == nil {
+		hostName = u.Host
+	}
+
+	t.mu.Lock()
+	h := t.host(hostName)
+	t.mu.Unlock()
+
+	// take a slot for the host first, so time spent queueing for it
+	// doesn't count against the rate limits
+	if h.conns != nil {
+		select {
+		case h.conns <- empty{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if h.conns != nil {
+			<-h.conns
+		}
+	}
+
+	// book the earliest time that suits both limits
+	t.mu.Lock()
+	at := time.Now()
+	if t.next.After(at) {
+		at = t.next
+	}
+	if h.next.After(at) {
+		at = h.next
+	}
+	t.next = at.Add(t.interval)
+	h.next = at.Add(t.hostInterval)
+	t.mu.Unlock()
+
+	if t.jitter > 0 {
+		at = at.Add(time.Duration(rand.Int63n(int64(t.jitter))))
+	}
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return release, nil
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// timelineEvents turns the dates on a document into timeline events
+func timelineEvents(result analysisResult, fileName string) (events []TimelineEvent) {
+	add := func(t time.Time, event, person, source string) {
+		if t.IsZero() {
+			return
+		}
+		events = append(events, TimelineEvent{
+			Time:     t,
+			Event:    event,
+			Person:   person,
+			Source:   source,
+			FileName: fileName,
+			FileUrl:  result.url,
+		})
+	}
+
+	add(result.created, "created", result.creator, "metadata")
+	add(result.modified, "modified", result.lastModifiedBy, "metadata")
+	// there's no telling who printed it
+	add(result.printed, "printed", "", "metadata")
+	add(result.crawled, "crawled", "", result.searchEngine)
+
+	return
+}
+
+// sortTimeline puts events in date order, oldest first
+func sortTimeline(events []TimelineEvent) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+}
+
+// writeTimeline writes events to fileName as CSV if it ends in .csv,
+// otherwise as JSON.
+func writeTimeline(events []TimelineEvent, fileName string) error {
+	if strings.EqualFold(filepath.Ext(fileName), ".csv") {
+		rows := [][]string{{"time", "event", "person", "source", "file_name", "file_url"}}
+		for _, e := range events {
+			rows = append(rows, []string{e.Time.Format(time.RFC3339), e.Event, e.Person, e.Source, e.FileName, e.FileUrl})
+		}
+		return writeCsvFile(fileName, rows)
+	}
+
+	jsonContent, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("unable to marshal timeline to JSON")
+	}
+	if err := os.WriteFile(fileName, jsonContent, 0644); err != nil {
+		return fmt.Errorf("error writing timeline to file")
+	}
+
+	return nil
+}
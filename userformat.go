@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// usernameFormat builds a username from a first and last name
+type usernameFormat struct {
+	name  string
+	build func(first, last string) string
+}
+
+// order matters - ties go to whichever format is listed first, so
+// the common formats sit at the top
+var usernameFormats = []usernameFormat{
+	{"first.last", func(f, l string) string { return f + "." + l }},
+	{"flast", func(f, l string) string { return f[:1] + l }},
+	{"firstl", func(f, l string) string { return f + l[:1] }},
+	{"f.last", func(f, l string) string { return f[:1] + "." + l }},
+	{"first_last", func(f, l string) string { return f + "_" + l }},
+	{"first-last", func(f, l string) string { return f + "-" + l }},
+	{"firstlast", func(f, l string) string { return f + l }},
+	{"last.first", func(f, l string) string { return l + "." + f }},
+	{"lastf", func(f, l string) string { return l + f[:1] }},
+	{"lastfirst", func(f, l string) string { return l + f }},
+	{"first", func(f, l string) string { return f }},
+	{"last", func(f, l string) string { return l }},
+}
+
+// inferUserFormat correlates names with usernames and email addresses
+// to work out the naming convention, then applies it to every name we
+// know about to build username and email lists.
+func inferUserFormat(results FinalResult) UserFormat {
+	var r UserFormat
+
+	// unique first/last pairs
+	var people [][2]string
+	for _, n := range results.Names {
+		first, last, ok := splitName(n.Name)
+		if !ok {
+			continue
+		}
+		if !slices.Contains(people, [2]string{first, last}) {
+			people = append(people, [2]string{first, last})
+		}
+	}
+
+	var usernames []string
+	for _, u := range results.Usernames {
+		username := strings.ToLower(u.UserName)
+		if !slices.Contains(usernames, username) {
+			usernames = append(usernames, username)
+		}
+	}
+
+	// local parts and the most common domain for emails. webmail
+	// domains aren't the target's, so we'd never generate emails for them
+	var localParts []string
+	var emails []string
+	domainCounts := make(map[string]int)
+	for _, e := range results.Emails {
+		email := strings.ToLower(e.EmailAddr)
+		local, domain, found := strings.Cut(email, "@")
+		if !found {
+			continue
+		}
+		if !slices.Contains(emails, email) {
+			emails = append(emails, email)
+		}
+		if !slices.Contains(localParts, local) {
+			localParts = append(localParts, local)
+		}
+		if !slices.Contains(freemailDomains, domain) {
+			domainCounts[domain]++
+		}
+	}
+	for domain, count := range domainCounts {
+		if count > domainCounts[r.EmailDomain] || (count == domainCounts[r.EmailDomain] && domain < r.EmailDomain) {
+			r.EmailDomain = domain
+		}
+	}
+
+	r.UsernameFormat, r.UsernameMatches = bestFormat(people, usernames)
+	r.EmailFormat, r.EmailMatches = bestFormat(people, localParts)
+
+	// build the lists - what we found, plus what the formats give us
+	r.Usernames = usernames
+	r.Emails = emails
+	for _, person := range people {
+		if f := formatByName(r.UsernameFormat); f != nil {
+			username := f.build(person[0], person[1])
+			if !slices.Contains(r.Usernames, username) {
+				r.Usernames = append(r.Usernames, username)
+			}
+		}
+		if f := formatByName(r.EmailFormat); f != nil && r.EmailDomain != "" {
+			email := f.build(person[0], person[1]) + "@" + r.EmailDomain
+			if !slices.Contains(r.Emails, email) {
+				r.Emails = append(r.Emails, email)
+			}
+		}
+	}
+	sort.Strings(r.Usernames)
+	sort.Strings(r.Emails)
+
+	return r
+}
+
+// bestFormat returns the format that explains the most candidates,
+// and how many it explained. Returns "" if nothing matched.
+func bestFormat(people [][2]string, candidates []string) (string, int) {
+	var best string
+	var bestCount int
+	for _, format := range usernameFormats {
+		count := 0
+		for _, person := range people {
+			if slices.Contains(candidates, format.build(person[0], person[1])) {
+				count++
+			}
+		}
+		if count > bestCount {
+			best = format.name
+			bestCount = count
+		}
+	}
+
+	return best, bestCount
+}
+
+// formatByName looks up a format, or nil if there isn't one
+func formatByName(name string) *usernameFormat {
+	for i := range usernameFormats {
+		if usernameFormats[i].name == name {
+			return &usernameFormats[i]
+		}
+	}
+	return nil
+}
+
+// splitName pulls a lower-case first and last name out of n. Handles
+// "John Smith", "Smith, John" and middle names. Single words and
+// anything with digits are skipped as they're probably usernames, as
+// are placeholders like "Microsoft Office User".
+func splitName(n string) (first, last string, ok bool) {
+	n = strings.TrimSpace(n)
+	if isBuiltinAccount(n) || strings.ContainsFunc(n, unicode.IsDigit) || strings.ContainsAny(n, `\/@`) {
+		return "", "", false
+	}
+
+	var parts []string
+	if before, after, found := strings.Cut(n, ","); found {
+		// "Smith, John"
+		parts = append(strings.Fields(after), strings.Fields(before)...)
+	} else {
+		parts = strings.Fields(n)
+	}
+	if len(parts) < 2 {
+		return "", "", false
+	}
+
+	first = cleanNamePart(parts[0])
+	last = cleanNamePart(parts[len(parts)-1])
+	if first == "" || last == "" {
+		return "", "", false
+	}
+
+	return first, last, true
+}
+
+// cleanNamePart lower-cases s and drops anything that won't be in a
+// username, like apostrophes and hyphens.
+func cleanNamePart(s string) string {
+	var sb strings.Builder
+	for _, c := range strings.ToLower(s) {
+		if unicode.IsLetter(c) && c < unicode.MaxASCII {
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+// writeUserLists writes the username wordlist to fileName, and the
+// email list alongside it with "-emails" added to the name.
+func writeUserLists(format UserFormat, fileName string) error {
+	if err := os.WriteFile(fileName, []byte(joinLines(format.Usernames)), 0644); err != nil {
+		return fmt.Errorf("unable to write username list: %w", err)
+	}
+
+	ext := filepath.Ext(fileName)
+	emailFile := strings.TrimSuffix(fileName, ext) + "-emails" + ext
+	if err := os.WriteFile(emailFile, []byte(joinLines(format.Emails)), 0644); err != nil {
+		return fmt.Errorf("unable to write email list: %w", err)
+	}
+
+	return nil
+}
+
+// joinLines puts one item per line, leaving an empty list as an empty
+// file rather than a lone blank line
+func joinLines(items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	return strings.Join(items, "\n") + "\n"
+}
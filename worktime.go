@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// histogram characters, quietest to busiest
+const histogramScale = " .:-=+*#%@"
+
+// hasOffset reports whether t came with a real UTC offset. Parsing
+// leaves timestamps with no offset (and Office's "Z" ones) in UTC, so
+// those tell us nothing about where the author was.
+func hasOffset(t time.Time) bool {
+	return t.Location() != time.UTC
+}
+
+// formatOffset turns seconds east of UTC into "+01:00" style
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d:%02d", sign, seconds/3600, (seconds%3600)/60)
+}
+
+// inferWorkPatterns uses the created and modified times in the timeline
+// to work out the organisation's timezone(s) and when each author
+// tends to be working.
+func inferWorkPatterns(events []TimelineEvent) WorkPatterns {
+	var r WorkPatterns
+
+	// only the author's own actions count - crawl dates are Bing's
+	// and nobody knows who printed what
+	var authored []TimelineEvent
+	for _, e := range events {
+		if (e.Event == "created" || e.Event == "modified") && e.Person != "" {
+			authored = append(authored, e)
+		}
+	}
+
+	// offsets across the whole organisation, and per author
+	orgOffsets := make(map[int]int)
+	personOffsets := make(map[string]map[int]int)
+	for _, e := range authored {
+		if !hasOffset(e.Time) {
+			continue
+		}
+		_, offset := e.Time.Zone()
+		orgOffsets[offset]++
+		if personOffsets[e.Person] == nil {
+			personOffsets[e.Person] = make(map[int]int)
+		}
+		personOffsets[e.Person][offset]++
+	}
+	for offset, count := range orgOffsets {
+		r.Offsets = append(r.Offsets, OffsetCount{Offset: formatOffset(offset), Count: count})
+	}
+	sort.Slice(r.Offsets, func(i, j int) bool {
+		if r.Offsets[i].Count != r.Offsets[j].Count {
+			return r.Offsets[i].Count > r.Offsets[j].Count
+		}
+		return r.Offsets[i].Offset < r.Offsets[j].Offset
+	})
+	orgOffset, orgKnown := mostCommon(orgOffsets)
+
+	// hours of the day for each author, in their local time. UTC-only
+	// timestamps get shifted by the author's usual offset, or the
+	// organisation's if we've not seen one for them.
+	hours := make(map[string]*AuthorHours)
+	var people []string
+	for _, e := range authored {
+		h, ok := hours[e.Person]
+		if !ok {
+			h = &AuthorHours{Person: e.Person}
+			hours[e.Person] = h
+			people = append(people, e.Person)
+		}
+
+		local := e.Time
+		if !hasOffset(local) {
+			if offset, known := mostCommon(personOffsets[e.Person]); known {
+				local = local.In(time.FixedZone("", offset))
+			} else if orgKnown {
+				local = local.In(time.FixedZone("", orgOffset))
+			}
+			h.Assumed++
+		}
+		h.Histogram[local.Hour()]++
+		h.Samples++
+	}
+
+	for _, person := range people {
+		h := hours[person]
+		for offset := range personOffsets[person] {
+			h.Offsets = append(h.Offsets, formatOffset(offset))
+		}
+		sort.Strings(h.Offsets)
+		h.Start, h.End = typicalHours(h.Histogram)
+		r.Authors = append(r.Authors, *h)
+	}
+	sort.SliceStable(r.Authors, func(i, j int) bool {
+		return r.Authors[i].Samples > r.Authors[j].Samples
+	})
+
+	return r
+}
+
+// mostCommon returns the offset seen most often
+func mostCommon(counts map[int]int) (int, bool) {
+	var best, bestCount int
+	for offset, count := range counts {
+		if count > bestCount || (count == bestCount && offset < best) {
+			best = offset
+			bestCount = count
+		}
+	}
+	return best, bestCount > 0
+}
+
+// typicalHours returns the hours that the middle 80% of activity falls
+// between, which trims off the odd late night.
+func typicalHours(histogram [24]int) (int, int) {
+	var samples []int
+	for hour, count := range histogram {
+		for i := 0; i < count; i++ {
+			samples = append(samples, hour)
+		}
+	}
+	if len(samples) == 0 {
+		return 0, 0
+	}
+
+	end := (len(samples) * 9) / 10
+	if end >= len(samples) {
+		end = len(samples) - 1
+	}
+	return samples[len(samples)/10], samples[end]
+}
+
+// renderHistogram draws a 24-hour histogram as one character per hour
+func renderHistogram(histogram [24]int) string {
+	var max int
+	for _, count := range histogram {
+		if count > max {
+			max = count
+		}
+	}
+
+	var sb strings.Builder
+	for _, count := range histogram {
+		if max == 0 || count == 0 {
+			sb.WriteByte(histogramScale[0])
+			continue
+		}
+		// anything non-zero gets at least the first mark
+		level := 1 + (count*(len(histogramScale)-2))/max
+		if level > len(histogramScale)-1 {
+			level = len(histogramScale) - 1
+		}
+		sb.WriteByte(histogramScale[level])
+	}
+
+	return sb.String()
+}